@@ -1,64 +1,116 @@
 package config
 
 import (
-	"os"
 	"time"
 )
 
+// Config 应用配置。加载顺序为 默认值（envDefault）→ 配置文件 → 环境变量（env）→ 命令行参数，
+// 命令行参数名为配置文件中的路径，如 -server.port；标记 secret 的字段在打印时隐藏，
+// 标记 reload 的字段可以在运行时重新加载，见 Store
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Redis    RedisConfig
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	JWT      JWTConfig      `yaml:"jwt"`
+	Redis    RedisConfig    `yaml:"redis"`
+	Image    ImageConfig    `yaml:"image"`
+	Storage  StorageConfig  `yaml:"storage"`
+	Task     TaskConfig     `yaml:"task"`
+
+	file string // 加载的配置文件路径
 }
 
 type ServerConfig struct {
-	Port string
-	Host string
+	Port string `yaml:"port" env:"SERVER_PORT" envDefault:"8081"`
+	Host string `yaml:"host" env:"SERVER_HOST" envDefault:"localhost"`
+	Mode string `yaml:"mode" env:"SERVER_MODE" envDefault:"development"` // development 或 production
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" envDefault:"30s"` // 停止时等待处理中的请求完成的时间
+	DrainDelay      time.Duration `yaml:"drain_delay" env:"SERVER_DRAIN_DELAY" envDefault:"0s"`            // 标记为未就绪后、停止接受连接前的等待时间，供负载均衡摘除实例
 }
 
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
+	Driver   string `yaml:"driver" env:"DB_DRIVER" envDefault:"mysql"` // mysql、postgres 或 sqlite
+	Host     string `yaml:"host" env:"DB_HOST" envDefault:"localhost"`
+	Port     string `yaml:"port" env:"DB_PORT" envDefault:"3306"`
+	User     string `yaml:"user" env:"DB_USER" envDefault:"root"`
+	Password string `yaml:"password" env:"DB_PASSWORD" envDefault:"root" secret:"true"`
+	DBName   string `yaml:"db_name" env:"DB_NAME" envDefault:"go_admin"`
+	SSLMode  string `yaml:"ssl_mode" env:"DB_SSL_MODE" envDefault:"disable"` // 仅 postgres
+	Path     string `yaml:"path" env:"DB_PATH" envDefault:"go_admin.db"`     // 仅 sqlite，数据库文件路径
+}
+
+type ImageConfig struct {
+	DefaultOwner    string          `yaml:"default_owner" env:"IMAGE_DEFAULT_OWNER" envDefault:"admin"`                                              // 历史图片迁移时归属的用户名
+	MaxFileSize     int64           `yaml:"max_file_size" env:"IMAGE_MAX_FILE_SIZE" envDefault:"10485760" reload:"true"`                             // 上传文件大小上限（字节）
+	MaxWidth        int             `yaml:"max_width" env:"IMAGE_MAX_WIDTH" envDefault:"10000" reload:"true"`                                        // 最大宽度（像素）
+	MaxHeight       int             `yaml:"max_height" env:"IMAGE_MAX_HEIGHT" envDefault:"10000" reload:"true"`                                      // 最大高度（像素）
+	MaxPixels       int64           `yaml:"max_pixels" env:"IMAGE_MAX_PIXELS" envDefault:"40000000" reload:"true"`                                   // 最大像素总数，防止解压炸弹
+	Variants        []VariantConfig `yaml:"variants" env:"IMAGE_VARIANTS" envDefault:"thumb:200,medium:800"`                                         // 尺寸变体
+	AllowedTypes    []string        `yaml:"allowed_types" env:"IMAGE_ALLOWED_TYPES" envDefault:"jpeg,png,gif" reload:"true"`                         // 允许上传的格式
+	TransformSizes  []int           `yaml:"transform_sizes" env:"IMAGE_TRANSFORM_SIZES" envDefault:"100,200,400,800,1200" reload:"true"`             // 动态变换允许的宽高值，防止任意尺寸刷满缓存
+	CodeLength      int             `yaml:"code_length" env:"IMAGE_CODE_LENGTH" envDefault:"8" reload:"true"`                                        // 图片码长度
+	CodeAlphabet    string          `yaml:"code_alphabet" env:"IMAGE_CODE_ALPHABET" envDefault:"0123456789abcdefghijklmnopqrstuvwxyz" reload:"true"` // 图片码字符集
+	CleanupInterval time.Duration   `yaml:"cleanup_interval" env:"IMAGE_CLEANUP_INTERVAL" envDefault:"1h"`                                           // 全量检查过期图片的间隔
+}
+
+// VariantConfig 尺寸变体配置，图片等比缩放到 Size×Size 以内
+type VariantConfig struct {
+	Name string `yaml:"name"`
+	Size int    `yaml:"size"`
+}
+
+type StorageConfig struct {
+	Driver   string   `yaml:"driver" env:"STORAGE_DRIVER" envDefault:"local"` // local 或 s3
+	LocalDir string   `yaml:"local_dir" env:"STORAGE_LOCAL_DIR" envDefault:"./uploads/images"`
+	S3       S3Config `yaml:"s3"`
+}
+
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint" env:"S3_ENDPOINT" envDefault:"localhost:9000"`
+	AccessKey string `yaml:"access_key" env:"S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secret_key" env:"S3_SECRET_KEY" secret:"true"`
+	Bucket    string `yaml:"bucket" env:"S3_BUCKET" envDefault:"go-admin-images"`
+	Region    string `yaml:"region" env:"S3_REGION"`
+	UseSSL    bool   `yaml:"use_ssl" env:"S3_USE_SSL" envDefault:"false"`
+}
+
+type TaskConfig struct {
+	Workers           int           `yaml:"workers" env:"TASK_WORKERS" envDefault:"4"`                                  // 每个队列的处理协程数
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" env:"TASK_SHUTDOWN_TIMEOUT" envDefault:"30s"`              // 停止时等待正在处理的任务完成的时间
+	VisibilityTimeout time.Duration `yaml:"visibility_timeout" env:"TASK_VISIBILITY_TIMEOUT" envDefault:"30s"`          // 处理进程失联超过该时间后，其未完成的任务重新入队
+	LeaderTTL         time.Duration `yaml:"leader_ttl" env:"TASK_LEADER_TTL" envDefault:"15s"`                          // 清理任务领导者租约时长，领导者失联超过该时间后由其他实例接替
+	MaxRetries        int           `yaml:"max_retries" env:"TASK_MAX_RETRIES" envDefault:"3" reload:"true"`            // 失败后最多重试次数，耗尽后进入死信队列
+	RetryBaseDelay    time.Duration `yaml:"retry_base_delay" env:"TASK_RETRY_BASE_DELAY" envDefault:"5s" reload:"true"` // 第一次重试的延迟，之后每次翻倍
+	RetryMaxDelay     time.Duration `yaml:"retry_max_delay" env:"TASK_RETRY_MAX_DELAY" envDefault:"10m" reload:"true"`  // 重试延迟上限
+	RetryJitter       float64       `yaml:"retry_jitter" env:"TASK_RETRY_JITTER" envDefault:"0.2" reload:"true"`        // 随机抖动比例（0-1），避免同时失败的任务同时重试
+	HistoryRetention  time.Duration `yaml:"history_retention" env:"TASK_HISTORY_RETENTION" envDefault:"720h"`           // 任务历史记录保留时长
 }
 
 type JWTConfig struct {
-	Secret     string
-	ExpireTime time.Duration
+	Secret            string        `yaml:"secret" env:"JWT_SECRET" envDefault:"your-secret-key" secret:"true"`
+	AccessExpireTime  time.Duration `yaml:"access_expire_time" env:"JWT_ACCESS_EXPIRE_TIME" envDefault:"15m"`    // 访问令牌有效期
+	RefreshExpireTime time.Duration `yaml:"refresh_expire_time" env:"JWT_REFRESH_EXPIRE_TIME" envDefault:"168h"` // 刷新令牌有效期
 }
 
-func LoadConfig() *Config {
-	return &Config{
-		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "8081"),
-			Host: getEnv("SERVER_HOST", "localhost"),
-		},
-		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "3306"),
-			User:     getEnv("DB_USER", "root"),
-			Password: getEnv("DB_PASSWORD", "root"),
-			DBName:   getEnv("DB_NAME", "go_admin"),
-		},
-		JWT: JWTConfig{
-			Secret:     getEnv("JWT_SECRET", "your-secret-key"),
-			ExpireTime: 24 * time.Hour, // 1天
-		},
-		Redis: RedisConfig{
-			Host:     getEnv("REDIS_HOST", "localhost"),
-			Port:     6379,
-			Password: getEnv("REDIS_PASSWORD", ""),
-			DB:       0,
-		},
-	}
+// IsProduction 是否为生产模式
+func (c *Config) IsProduction() bool {
+	return c.Server.Mode == ModeProduction
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
+// File 加载的配置文件路径，未使用配置文件时为空
+func (c *Config) File() string {
+	return c.file
 }
+
+// 运行模式
+const (
+	ModeDevelopment = "development"
+	ModeProduction  = "production"
+)
+
+// 数据库类型
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
@@ -0,0 +1,381 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue 打印配置时代替敏感字段
+const redactedValue = "******"
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	variantsType = reflect.TypeOf([]VariantConfig(nil))
+	intsType     = reflect.TypeOf([]int(nil))
+	stringsType  = reflect.TypeOf([]string(nil))
+)
+
+// field 配置项
+type field struct {
+	path  string // 配置文件中的路径，同时用作命令行参数名，如 server.port
+	env   string
+	value reflect.Value
+	tag   reflect.StructTag
+}
+
+// Load 按 默认值 → 配置文件 → 环境变量 → 命令行参数 的顺序加载配置并校验。
+// 配置文件由 -config 参数或 CONFIG_FILE 环境变量指定，支持 YAML 和 TOML
+func Load(args []string) (*Config, error) {
+	cfg, fields, err := defaults()
+	if err != nil {
+		return nil, err
+	}
+
+	// 先解析命令行参数得到配置文件路径，参数值在环境变量之后再应用
+	flags := newFlagSet(fields)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "配置文件路径（YAML 或 TOML），环境变量 CONFIG_FILE")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// 配置文件
+	if *configFile != "" {
+		if err := loadFile(cfg, *configFile); err != nil {
+			return nil, err
+		}
+		cfg.file = *configFile
+	}
+
+	// 环境变量
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if value, ok := os.LookupEnv(f.env); ok && value != "" {
+			if err := setValue(f.value, value); err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", f.env, err)
+			}
+		}
+	}
+
+	// 命令行参数
+	var flagErr error
+	flags.Visit(func(fl *flag.Flag) {
+		if fl.Name == "config" || flagErr != nil {
+			return
+		}
+		if err := setValue(fl.Value.(*flagValue).field.value, fl.Value.String()); err != nil {
+			flagErr = fmt.Errorf("invalid -%s: %v", fl.Name, err)
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// PrintDefaults 输出全部命令行参数及对应的环境变量
+func PrintDefaults(w io.Writer) {
+	_, fields, err := defaults()
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return
+	}
+	flags := newFlagSet(fields)
+	flags.String("config", "", "配置文件路径（YAML 或 TOML），环境变量 CONFIG_FILE")
+	flags.SetOutput(w)
+	flags.PrintDefaults()
+}
+
+// defaults 创建填充了默认值的配置
+func defaults() (*Config, []field, error) {
+	cfg := &Config{}
+	fields := collectFields(reflect.ValueOf(cfg).Elem(), "")
+	for _, f := range fields {
+		if def, ok := f.tag.Lookup("envDefault"); ok {
+			if err := setValue(f.value, def); err != nil {
+				return nil, nil, fmt.Errorf("invalid default for %s: %v", f.path, err)
+			}
+		}
+	}
+	return cfg, fields, nil
+}
+
+// Redacted 返回隐藏敏感字段后的配置副本
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.Image.Variants = append([]VariantConfig(nil), c.Image.Variants...)
+	redacted.Image.TransformSizes = append([]int(nil), c.Image.TransformSizes...)
+	redacted.Image.AllowedTypes = append([]string(nil), c.Image.AllowedTypes...)
+	for _, f := range collectFields(reflect.ValueOf(&redacted).Elem(), "") {
+		if f.tag.Get("secret") == "true" && f.value.String() != "" {
+			f.value.SetString(redactedValue)
+		}
+	}
+	return &redacted
+}
+
+// YAML 以YAML格式输出配置
+func (c *Config) YAML() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// loadFile 读取配置文件，未知的配置项视为错误
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+	case ".toml":
+		// 转换为YAML后统一解析，时间间隔可以写成 "15m" 形式
+		var values map[string]interface{}
+		if err := toml.Unmarshal(data, &values); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %v", path, err)
+		}
+		if data, err = yaml.Marshal(values); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %v", path, err)
+		}
+	default:
+		return fmt.Errorf("unsupported config file format: %s", path)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// collectFields 递归收集全部配置项
+func collectFields(v reflect.Value, prefix string) []field {
+	var fields []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := sf.Tag.Get("yaml")
+		if name == "" || name == "-" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		value := v.Field(i)
+		if value.Kind() == reflect.Struct && value.Type() != durationType {
+			fields = append(fields, collectFields(value, path)...)
+			continue
+		}
+		fields = append(fields, field{
+			path:  path,
+			env:   sf.Tag.Get("env"),
+			value: value,
+			tag:   sf.Tag,
+		})
+	}
+	return fields
+}
+
+// flagValue 命令行参数，只记录原始值，在环境变量之后统一应用
+type flagValue struct {
+	field *field
+	raw   string
+	set   bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil || v.field == nil {
+		return ""
+	}
+	if v.set {
+		return v.raw
+	}
+	return formatValue(v.field.value)
+}
+
+func (v *flagValue) Set(raw string) error {
+	// 提前校验格式，错误在解析参数时就能给出
+	probe := reflect.New(v.field.value.Type()).Elem()
+	if err := setValue(probe, raw); err != nil {
+		return err
+	}
+	v.raw = raw
+	v.set = true
+	return nil
+}
+
+// IsBoolFlag 布尔参数可以省略值
+func (v *flagValue) IsBoolFlag() bool {
+	return v.field != nil && v.field.value.Kind() == reflect.Bool
+}
+
+// newFlagSet 为每个配置项注册命令行参数
+func newFlagSet(fields []field) *flag.FlagSet {
+	flags := flag.NewFlagSet("go-admin", flag.ContinueOnError)
+	for i := range fields {
+		f := &fields[i]
+		usage := f.path
+		if f.env != "" {
+			usage = "环境变量 " + f.env
+		}
+		flags.Var(&flagValue{field: f}, f.path, usage)
+	}
+	return flags
+}
+
+// setValue 将字符串形式的配置值写入字段
+func setValue(v reflect.Value, raw string) error {
+	switch v.Type() {
+	case durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case variantsType:
+		variants, err := parseVariants(raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(variants))
+		return nil
+	case intsType:
+		values, err := parseIntList(raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(values))
+		return nil
+	case stringsType:
+		v.Set(reflect.ValueOf(parseStringList(raw)))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
+
+// formatValue 将字段格式化为与 setValue 对应的字符串
+func formatValue(v reflect.Value) string {
+	switch v.Type() {
+	case durationType:
+		return time.Duration(v.Int()).String()
+	case variantsType:
+		items := make([]string, 0, v.Len())
+		for _, variant := range v.Interface().([]VariantConfig) {
+			items = append(items, fmt.Sprintf("%s:%d", variant.Name, variant.Size))
+		}
+		return strings.Join(items, ",")
+	case intsType:
+		items := make([]string, 0, v.Len())
+		for _, n := range v.Interface().([]int) {
+			items = append(items, strconv.Itoa(n))
+		}
+		return strings.Join(items, ",")
+	case stringsType:
+		return strings.Join(v.Interface().([]string), ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// parseVariants 解析 "thumb:200,medium:800" 形式的变体配置
+func parseVariants(value string) ([]VariantConfig, error) {
+	var variants []VariantConfig
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, sizeStr, found := strings.Cut(item, ":")
+		if !found {
+			return nil, fmt.Errorf("variant %q must be name:size", item)
+		}
+		size, err := strconv.Atoi(sizeStr)
+		if err != nil {
+			return nil, fmt.Errorf("variant %q has invalid size", item)
+		}
+		variants = append(variants, VariantConfig{Name: name, Size: size})
+	}
+	return variants, nil
+}
+
+// parseIntList 解析逗号分隔的整数列表
+func parseIntList(value string) ([]int, error) {
+	var values []int
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		n, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", item)
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+// parseStringList 解析逗号分隔的字符串列表
+func parseStringList(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
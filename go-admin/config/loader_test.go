@@ -0,0 +1,245 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv 清空全部配置项对应的环境变量，避免测试受运行环境影响
+func clearEnv(t *testing.T) {
+	t.Helper()
+	_, fields, err := defaults()
+	if err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", "")
+	for _, f := range fields {
+		if f.env != "" {
+			t.Setenv(f.env, "")
+		}
+	}
+}
+
+// writeFile 在临时目录中写入配置文件
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+server:
+  port: "9001"
+  host: file-host
+image:
+  max_width: 2000
+  max_height: 2000
+`)
+	t.Setenv("SERVER_PORT", "9002")
+	t.Setenv("IMAGE_MAX_WIDTH", "3000")
+
+	cfg, err := Load([]string{"-config", path, "-server.port", "9003"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"default", cfg.Image.MaxPixels, int64(40000000)},
+		{"file over default", cfg.Image.MaxHeight, 2000},
+		{"file only", cfg.Server.Host, "file-host"},
+		{"env over file", cfg.Image.MaxWidth, 3000},
+		{"flag over env", cfg.Server.Port, "9003"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if cfg.File() != path {
+		t.Errorf("File() = %q, want %q", cfg.File(), path)
+	}
+}
+
+func TestLoadConfigFileFromEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.yml", "server:\n  port: \"9001\"\n"))
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server.Port != "9001" {
+		t.Errorf("server.port = %s, want 9001", cfg.Server.Port)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	clearEnv(t)
+	yamlFile := writeFile(t, "config.yaml", `
+server:
+  port: "9001"
+  shutdown_timeout: 45s
+jwt:
+  access_expire_time: 10m
+image:
+  max_file_size: 2048
+  allowed_types: [png]
+  variants:
+    - name: small
+      size: 100
+task:
+  retry_jitter: 0.5
+`)
+	tomlFile := writeFile(t, "config.toml", `
+[server]
+port = "9001"
+shutdown_timeout = "45s"
+
+[jwt]
+access_expire_time = "10m"
+
+[image]
+max_file_size = 2048
+allowed_types = ["png"]
+variants = [{ name = "small", size = 100 }]
+
+[task]
+retry_jitter = 0.5
+`)
+
+	fromYAML, err := Load([]string{"-config", yamlFile})
+	if err != nil {
+		t.Fatalf("Load YAML: %v", err)
+	}
+	fromTOML, err := Load([]string{"-config", tomlFile})
+	if err != nil {
+		t.Fatalf("Load TOML: %v", err)
+	}
+	if fromTOML.Server.ShutdownTimeout != 45*time.Second || fromTOML.JWT.AccessExpireTime != 10*time.Minute {
+		t.Errorf("durations = %v, %v; want 45s, 10m", fromTOML.Server.ShutdownTimeout, fromTOML.JWT.AccessExpireTime)
+	}
+
+	// 除文件路径外与等价的YAML配置一致
+	fromTOML.file = fromYAML.file
+	if !reflect.DeepEqual(fromYAML, fromTOML) {
+		t.Errorf("TOML config = %+v, want %+v", fromTOML, fromYAML)
+	}
+}
+
+func TestLoadRejectsInvalidFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown.yaml", "server:\n  listen: 80\n", "field listen not found"},
+		{"unknown.toml", "[server]\nlisten = 80\n", "field listen not found"},
+		{"broken.toml", "[server\n", "failed to parse config file"},
+		{"config.json", "{}", "unsupported config file format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			_, err := Load([]string{"-config", writeFile(t, tt.name, tt.content)})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Load = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg, _, err := defaults()
+	if err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+	var secrets []string
+	for _, f := range collectFields(reflect.ValueOf(cfg).Elem(), "") {
+		if f.tag.Get("secret") == "true" {
+			value := "secret-value-of-" + f.path
+			f.value.SetString(value)
+			secrets = append(secrets, value)
+		}
+	}
+	if len(secrets) == 0 {
+		t.Fatal("no secret fields")
+	}
+
+	out, err := cfg.Redacted().YAML()
+	if err != nil {
+		t.Fatalf("YAML: %v", err)
+	}
+	for _, secret := range secrets {
+		if strings.Contains(string(out), secret) {
+			t.Errorf("config print contains %q", secret)
+		}
+	}
+	if n := strings.Count(string(out), redactedValue); n != len(secrets) {
+		t.Errorf("config print has %d redacted values, want %d", n, len(secrets))
+	}
+
+	// 原配置不受影响
+	if cfg.JWT.Secret != "secret-value-of-jwt.secret" {
+		t.Errorf("Redacted modified the original config: jwt.secret = %q", cfg.JWT.Secret)
+	}
+}
+
+func TestValidateJWTSecretInProduction(t *testing.T) {
+	tests := []struct {
+		mode   string
+		secret string
+		want   string
+	}{
+		{ModeDevelopment, defaultJWTSecret, ""},
+		{ModeProduction, defaultJWTSecret, "jwt.secret must be changed from the default in production"},
+		{ModeProduction, "short-secret", "jwt.secret must be at least 32 characters in production"},
+		{ModeProduction, strings.Repeat("s", minProductionSecretLength), ""},
+	}
+	for _, tt := range tests {
+		cfg, _, err := defaults()
+		if err != nil {
+			t.Fatalf("defaults: %v", err)
+		}
+		cfg.Server.Mode = tt.mode
+		cfg.JWT.Secret = tt.secret
+
+		err = cfg.Validate()
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s with %q: Validate = %v, want nil", tt.mode, tt.secret, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s with %q: Validate = %v, want %q", tt.mode, tt.secret, err, tt.want)
+		}
+	}
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	cfg, _, err := defaults()
+	if err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+	cfg.Server.Port = "0"
+	cfg.Task.Workers = 0
+
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate = nil, want errors")
+	}
+	want := "invalid configuration:\nserver.port must be between 1 and 65535\ntask.workers must be at least 1"
+	if err.Error() != want {
+		t.Errorf("Validate = %q, want %q", err, want)
+	}
+}
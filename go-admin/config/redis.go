@@ -10,10 +10,10 @@ import (
 
 // Redis配置
 type RedisConfig struct {
-	Host     string `env:"REDIS_HOST" envDefault:"localhost"`
-	Port     int    `env:"REDIS_PORT" envDefault:"6379"`
-	Password string `env:"REDIS_PASSWORD" envDefault:""`
-	DB       int    `env:"REDIS_DB" envDefault:"0"`
+	Host     string `yaml:"host" env:"REDIS_HOST" envDefault:"localhost"`
+	Port     int    `yaml:"port" env:"REDIS_PORT" envDefault:"6379"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD" envDefault:"" secret:"true"`
+	DB       int    `yaml:"db" env:"REDIS_DB" envDefault:"0"`
 }
 
 // Redis常量
@@ -24,13 +24,31 @@ const (
 	ImageDeleteChannel = "image:delete:channel" // 图片删除频道
 	ImageExpireChannel = "image:expire:channel" // 图片过期频道
 
+	// 实时事件频道，推送任务进度和图片变更
+	EventChannel = "app:events"
+
+	// 图片过期时间表，图片ID按过期时间排序，到期后生成过期任务
+	ImageExpireSchedule = "image:expire:schedule"
+
+	// 处理中列表的键名中缀，见 ProcessingQueueKey
+	taskProcessingInfix = ":processing:"
+
+	// 动态变换缓存索引，记录每张图片的缓存对象
+	ImageTransformCachePrefix = "image:transform:"
+
 	// 任务状态
 	TaskStatusPending    = "pending"
 	TaskStatusProcessing = "processing"
 	TaskStatusCompleted  = "completed"
 	TaskStatusFailed     = "failed"
+	TaskStatusCancelled  = "cancelled"
 )
 
+// ProcessingQueueKey 处理进程取出但尚未确认的任务列表
+func ProcessingQueueKey(queue, workerID string) string {
+	return queue + taskProcessingInfix + workerID
+}
+
 // Redis客户端
 var RedisClient *redis.Client
 
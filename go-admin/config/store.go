@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// fileCheckInterval 检查配置文件是否被修改的间隔
+const fileCheckInterval = 2 * time.Second
+
+// Store 当前生效的配置。收到 SIGHUP 或配置文件被修改时重新加载，
+// 只替换标记 reload 的字段，其余字段的修改需要重启才能生效。
+// 配置整体原子替换且替换后不再修改，请求开始时调用一次 Get 即可得到一致的快照
+type Store struct {
+	args    []string
+	current atomic.Pointer[Config]
+	mu      sync.Mutex // 串行化重新加载
+
+	checkInterval time.Duration // 检查配置文件是否被修改的间隔
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewStore 创建配置存储，args 为加载配置时使用的命令行参数，重新加载时沿用
+func NewStore(cfg *Config, args []string) *Store {
+	s := &Store{args: args, checkInterval: fileCheckInterval}
+	s.current.Store(cfg)
+	return s
+}
+
+// Get 获取当前配置，返回值不可修改
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// Reload 重新加载配置，校验失败时保留当前配置
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loaded, err := Load(s.args)
+	if err != nil {
+		return err
+	}
+
+	old := s.Get()
+	next := *old
+	var changes, ignored []string
+	oldFields := collectFields(reflect.ValueOf(old).Elem(), "")
+	loadedFields := collectFields(reflect.ValueOf(loaded).Elem(), "")
+	nextFields := collectFields(reflect.ValueOf(&next).Elem(), "")
+	for i, f := range loadedFields {
+		if reflect.DeepEqual(oldFields[i].value.Interface(), f.value.Interface()) {
+			continue
+		}
+		if f.tag.Get("reload") != "true" {
+			ignored = append(ignored, f.path)
+			continue
+		}
+		nextFields[i].value.Set(f.value)
+		changes = append(changes, fmt.Sprintf("%s: %s -> %s", f.path, displayValue(oldFields[i]), displayValue(f)))
+	}
+
+	if len(ignored) > 0 {
+		log.Printf("Config changes require restart, ignored: %s", strings.Join(ignored, ", "))
+	}
+	if len(changes) == 0 {
+		log.Println("Config reloaded, no changes")
+		return nil
+	}
+	if err := next.Validate(); err != nil {
+		return err
+	}
+
+	s.current.Store(&next)
+	log.Printf("Config reloaded:\n  %s", strings.Join(changes, "\n  "))
+	return nil
+}
+
+// Watch 在后台监听 SIGHUP 和配置文件修改，触发重新加载
+func (s *Store) Watch() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	// 在返回前记录修改时间，之后的修改都能被检测到
+	modTime := fileModTime(s.Get().File())
+
+	go func() {
+		defer close(s.done)
+		defer signal.Stop(hup)
+
+		ticker := time.NewTicker(s.checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-hup:
+				log.Println("Received SIGHUP, reloading config")
+				modTime = fileModTime(s.Get().File())
+				s.reload()
+			case <-ticker.C:
+				if file := s.Get().File(); file != "" {
+					if current := fileModTime(file); !current.Equal(modTime) {
+						modTime = current
+						log.Printf("Config file %s changed, reloading config", file)
+						s.reload()
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止监听
+func (s *Store) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+}
+
+// reload 重新加载配置，失败时只记录日志
+func (s *Store) reload() {
+	if err := s.Reload(); err != nil {
+		log.Printf("Failed to reload config, keeping current config: %v", err)
+	}
+}
+
+// fileModTime 获取文件修改时间，文件不存在时返回零值
+func fileModTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// displayValue 格式化配置项用于日志，敏感字段隐藏
+func displayValue(f field) string {
+	if f.tag.Get("secret") == "true" {
+		return redactedValue
+	}
+	return formatValue(f.value)
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const storeTestConfig = `
+server:
+  port: "9001"
+jwt:
+  access_expire_time: 10m
+image:
+  max_file_size: 1024
+task:
+  max_retries: 2
+`
+
+// newTestStore 从配置文件加载配置并创建存储，返回配置文件路径
+func newTestStore(t *testing.T, content string) (*Store, string) {
+	t.Helper()
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", content)
+	args := []string{"-config", path}
+	cfg, err := Load(args)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return NewStore(cfg, args), path
+}
+
+// rewrite 修改配置文件，修改时间设为未来以确保能被检测到
+func rewrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("rewrite config: %v", err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+}
+
+// captureLog 记录测试期间输出的日志
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func TestStoreReloadAppliesOnlyReloadableFields(t *testing.T) {
+	s, path := newTestStore(t, storeTestConfig)
+	old := s.Get()
+	logs := captureLog(t)
+
+	rewrite(t, path, `
+server:
+  port: "9002"
+jwt:
+  access_expire_time: 20m
+image:
+  max_file_size: 2048
+task:
+  max_retries: 5
+`)
+	if err := s.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	current := s.Get()
+	if current.Image.MaxFileSize != 2048 || current.Task.MaxRetries != 5 {
+		t.Errorf("reloadable fields = %d, %d; want 2048, 5", current.Image.MaxFileSize, current.Task.MaxRetries)
+	}
+	// 其余字段保持启动时的值
+	oldFields := collectFields(reflect.ValueOf(old).Elem(), "")
+	for i, f := range collectFields(reflect.ValueOf(current).Elem(), "") {
+		if f.tag.Get("reload") != "true" && !reflect.DeepEqual(f.value.Interface(), oldFields[i].value.Interface()) {
+			t.Errorf("non-reloadable %s changed: %v -> %v", f.path, oldFields[i].value.Interface(), f.value.Interface())
+		}
+	}
+	// 之前取得的快照不受影响
+	if old.Image.MaxFileSize != 1024 {
+		t.Errorf("old snapshot changed: image.max_file_size = %d", old.Image.MaxFileSize)
+	}
+
+	output := logs.String()
+	if !strings.Contains(output, "ignored: server.port, jwt.access_expire_time") {
+		t.Errorf("ignored fields not reported, log:\n%s", output)
+	}
+	if !strings.Contains(output, "image.max_file_size: 1024 -> 2048") {
+		t.Errorf("applied change not logged, log:\n%s", output)
+	}
+}
+
+func TestStoreReloadIgnoresOnlyNonReloadableChanges(t *testing.T) {
+	s, path := newTestStore(t, storeTestConfig)
+	old := s.Get()
+	captureLog(t)
+
+	rewrite(t, path, strings.Replace(storeTestConfig, `port: "9001"`, `port: "9002"`, 1))
+	if err := s.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if s.Get() != old {
+		t.Error("config replaced although only non-reloadable fields changed")
+	}
+}
+
+func TestStoreReloadKeepsConfigOnInvalidFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"syntax error", "image: [\n"},
+		{"unknown field", storeTestConfig + "  unknown: 1\n"},
+		{"invalid value", strings.Replace(storeTestConfig, "max_file_size: 1024", "max_file_size: 0", 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, path := newTestStore(t, storeTestConfig)
+			old := s.Get()
+
+			rewrite(t, path, tt.content)
+			if err := s.Reload(); err == nil {
+				t.Fatal("Reload = nil, want error")
+			}
+			if s.Get() != old {
+				t.Error("config replaced by an invalid file")
+			}
+		})
+	}
+}
+
+// waitFor 等待条件满足
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStoreWatchReloadsOnFileChange(t *testing.T) {
+	s, path := newTestStore(t, storeTestConfig)
+	s.checkInterval = 10 * time.Millisecond
+	captureLog(t)
+	s.Watch()
+	t.Cleanup(s.Stop)
+
+	rewrite(t, path, strings.Replace(storeTestConfig, "max_file_size: 1024", "max_file_size: 4096", 1))
+	waitFor(t, "file change to be applied", func() bool { return s.Get().Image.MaxFileSize == 4096 })
+
+	// 修改为不合法的配置后保留当前配置
+	rewrite(t, path, "image: [\n")
+	time.Sleep(50 * time.Millisecond)
+	if s.Get().Image.MaxFileSize != 4096 {
+		t.Errorf("image.max_file_size = %d after invalid change, want 4096", s.Get().Image.MaxFileSize)
+	}
+}
+
+func TestStoreWatchReloadsOnSIGHUP(t *testing.T) {
+	s, path := newTestStore(t, storeTestConfig)
+	s.checkInterval = time.Hour
+	captureLog(t)
+	s.Watch()
+	t.Cleanup(s.Stop)
+
+	rewrite(t, path, strings.Replace(storeTestConfig, "max_retries: 2", "max_retries: 7", 1))
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("send SIGHUP: %v", err)
+	}
+	waitFor(t, "SIGHUP reload", func() bool { return s.Get().Task.MaxRetries == 7 })
+}
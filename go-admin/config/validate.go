@@ -0,0 +1,140 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultJWTSecret 示例密钥，生产模式下拒绝使用
+const defaultJWTSecret = "your-secret-key"
+
+// minProductionSecretLength 生产模式下JWT密钥的最小长度
+const minProductionSecretLength = 32
+
+// supportedImageTypes 图片检测支持的格式，与 imaging 包一致
+var supportedImageTypes = map[string]bool{"jpeg": true, "png": true, "gif": true}
+
+// maxImageCodeLength 图片码最大长度，与 images.image_code 列宽一致
+const maxImageCodeLength = 50
+
+// Validate 校验配置，返回全部不合法的配置项
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	// 服务
+	check(c.Server.Mode == ModeDevelopment || c.Server.Mode == ModeProduction,
+		"server.mode must be %q or %q", ModeDevelopment, ModeProduction)
+	check(validPort(c.Server.Port), "server.port must be between 1 and 65535")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drain_delay must not be negative")
+
+	// 数据库
+	switch c.Database.Driver {
+	case DriverMySQL, DriverPostgres:
+		check(c.Database.Host != "", "database.host is required")
+		check(validPort(c.Database.Port), "database.port must be between 1 and 65535")
+		check(c.Database.DBName != "", "database.db_name is required")
+	case DriverSQLite:
+		check(c.Database.Path != "", "database.path is required")
+	default:
+		errs = append(errs, fmt.Errorf("database.driver must be %q, %q or %q", DriverMySQL, DriverPostgres, DriverSQLite))
+	}
+
+	// JWT
+	check(c.JWT.Secret != "", "jwt.secret is required")
+	if c.IsProduction() {
+		check(c.JWT.Secret != defaultJWTSecret, "jwt.secret must be changed from the default in production")
+		check(len(c.JWT.Secret) >= minProductionSecretLength,
+			"jwt.secret must be at least %d characters in production", minProductionSecretLength)
+	}
+	check(c.JWT.AccessExpireTime > 0, "jwt.access_expire_time must be positive")
+	check(c.JWT.RefreshExpireTime > c.JWT.AccessExpireTime, "jwt.refresh_expire_time must be longer than jwt.access_expire_time")
+
+	// Redis
+	check(c.Redis.Host != "", "redis.host is required")
+	check(c.Redis.Port > 0 && c.Redis.Port <= 65535, "redis.port must be between 1 and 65535")
+	check(c.Redis.DB >= 0, "redis.db must not be negative")
+
+	// 图片
+	check(c.Image.MaxFileSize > 0, "image.max_file_size must be positive")
+	check(c.Image.MaxWidth > 0, "image.max_width must be positive")
+	check(c.Image.MaxHeight > 0, "image.max_height must be positive")
+	check(c.Image.MaxPixels > 0, "image.max_pixels must be positive")
+	names := make(map[string]bool)
+	for _, variant := range c.Image.Variants {
+		check(variant.Name != "" && variant.Name != "original", "image.variants: invalid variant name %q", variant.Name)
+		check(!names[variant.Name], "image.variants: duplicate variant %q", variant.Name)
+		check(variant.Size > 0, "image.variants: size of %q must be positive", variant.Name)
+		names[variant.Name] = true
+	}
+	check(len(c.Image.AllowedTypes) > 0, "image.allowed_types must not be empty")
+	for _, imageType := range c.Image.AllowedTypes {
+		check(supportedImageTypes[imageType], "image.allowed_types: unsupported type %q", imageType)
+	}
+	for _, size := range c.Image.TransformSizes {
+		check(size > 0, "image.transform_sizes must be positive")
+	}
+	check(c.Image.CodeLength >= 4 && c.Image.CodeLength <= maxImageCodeLength,
+		"image.code_length must be between 4 and %d", maxImageCodeLength)
+	check(validAlphabet(c.Image.CodeAlphabet),
+		"image.code_alphabet must contain at least 2 distinct URL-safe ASCII characters without duplicates")
+	check(c.Image.CleanupInterval >= time.Minute, "image.cleanup_interval must be at least 1m")
+
+	// 存储
+	switch c.Storage.Driver {
+	case "local":
+		check(c.Storage.LocalDir != "", "storage.local_dir is required")
+	case "s3":
+		check(c.Storage.S3.Endpoint != "", "storage.s3.endpoint is required")
+		check(c.Storage.S3.Bucket != "", "storage.s3.bucket is required")
+		check(c.Storage.S3.AccessKey != "" && c.Storage.S3.SecretKey != "", "storage.s3.access_key and storage.s3.secret_key are required")
+	default:
+		errs = append(errs, fmt.Errorf("storage.driver must be \"local\" or \"s3\""))
+	}
+
+	// 后台任务
+	check(c.Task.Workers >= 1, "task.workers must be at least 1")
+	check(c.Task.ShutdownTimeout > 0, "task.shutdown_timeout must be positive")
+	check(c.Task.VisibilityTimeout >= 3*time.Second, "task.visibility_timeout must be at least 3s")
+	check(c.Task.LeaderTTL >= 3*time.Second, "task.leader_ttl must be at least 3s")
+	check(c.Task.MaxRetries >= 0, "task.max_retries must not be negative")
+	check(c.Task.RetryBaseDelay > 0, "task.retry_base_delay must be positive")
+	check(c.Task.RetryMaxDelay >= c.Task.RetryBaseDelay, "task.retry_max_delay must not be shorter than task.retry_base_delay")
+	check(c.Task.RetryJitter >= 0 && c.Task.RetryJitter <= 1, "task.retry_jitter must be between 0 and 1")
+	check(c.Task.HistoryRetention > 0, "task.history_retention must be positive")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// validPort 端口号是否合法
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// validAlphabet 字符集是否至少包含两个不重复的URL安全字符
+func validAlphabet(alphabet string) bool {
+	if len(alphabet) < 2 {
+		return false
+	}
+	seen := make(map[rune]bool)
+	for _, r := range alphabet {
+		urlSafe := r < 128 && (r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || strings.ContainsRune("-_", r))
+		if !urlSafe || seen[r] {
+			return false
+		}
+		seen[r] = true
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"path"
+
+	"go-admin/config"
+	"go-admin/models"
+
+	"gorm.io/gorm"
+)
+
+// dataMigrations 需要读取配置或逐行处理的数据迁移，与SQL迁移共用版本号，所有数据库类型通用。
+// 数据迁移不可逆，回滚时只删除迁移记录
+var dataMigrations = []Migration{
+	{Version: 4, Name: "backfill_image_owners", run: backfillImageOwners},
+	{Version: 5, Name: "normalize_image_file_paths", run: normalizeImageFilePaths},
+}
+
+// backfillImageOwners 将没有上传者的历史图片归属到 IMAGE_DEFAULT_OWNER 指定的用户
+func backfillImageOwners(tx *gorm.DB, cfg *config.Config) error {
+	var count int64
+	if err := tx.Model(&models.Image{}).Where("owner_id = 0").Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+
+	var owner models.User
+	if err := tx.Where("username = ?", cfg.Image.DefaultOwner).First(&owner).Error; err != nil {
+		return fmt.Errorf("default image owner %q not found: %v", cfg.Image.DefaultOwner, err)
+	}
+
+	if err := tx.Model(&models.Image{}).Where("owner_id = 0").Update("owner_id", owner.ID).Error; err != nil {
+		return err
+	}
+
+	log.Printf("Assigned %d legacy images to user %s", count, owner.Username)
+	return nil
+}
+
+// normalizeImageFilePaths 早期版本file_path保存的是 ./uploads/images/xxx 形式的本地路径，
+// 接入存储后端后统一改为相对于存储根目录的对象键
+func normalizeImageFilePaths(tx *gorm.DB, cfg *config.Config) error {
+	var images []models.Image
+	if err := tx.Select("id", "file_path").Where("file_path LIKE ?", "%/%").Find(&images).Error; err != nil {
+		return err
+	}
+
+	for _, image := range images {
+		key := path.Base(image.FilePath)
+		if err := tx.Model(&models.Image{}).Where("id = ?", image.ID).Update("file_path", key).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(images) > 0 {
+		log.Printf("Converted %d legacy image file paths to storage keys", len(images))
+	}
+	return nil
+}
@@ -6,57 +6,143 @@ import (
 
 	"go-admin/config"
 	"go-admin/models"
+	"go-admin/utils"
 
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
-// InitDatabase 初始化数据库连接
-func InitDatabase(cfg *config.Config) error {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		cfg.Database.User,
-		cfg.Database.Password,
-		cfg.Database.Host,
-		cfg.Database.Port,
-		cfg.Database.DBName,
-	)
-
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+// InitDatabase 初始化数据库连接，数据库结构不是最新时拒绝启动
+func InitDatabase(cfg *config.Config, hasher utils.PasswordHasher) error {
+	if err := Connect(&cfg.Database); err != nil {
+		return err
+	}
+
+	// 检查数据库迁移，表结构由 migrate 子命令维护
+	if err := CheckMigrations(); err != nil {
+		return err
+	}
+
+	// 初始化角色与权限
+	if err := SeedRBAC(); err != nil {
+		return fmt.Errorf("failed to seed roles and permissions: %v", err)
+	}
+
+	// 初始化默认用户
+	if err := InitDefaultUsers(hasher); err != nil {
+		return fmt.Errorf("failed to init default users: %v", err)
+	}
+
+	log.Println("Database initialized successfully")
+	return nil
+}
+
+// Connect 按配置的数据库类型连接数据库
+func Connect(cfg *config.DatabaseConfig) error {
+	dialector, err := openDialector(cfg)
+	if err != nil {
+		return err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Info),
+		TranslateError: true, // 唯一索引冲突转换为 gorm.ErrDuplicatedKey
 	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %v", err)
 	}
 
 	DB = db
+	return nil
+}
 
-	// 自动迁移数据库表
-	if err := AutoMigrate(); err != nil {
-		return fmt.Errorf("failed to migrate database: %v", err)
+// Close 关闭数据库连接
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
 	}
+	return sqlDB.Close()
+}
 
-	// 初始化默认用户
-	if err := InitDefaultUsers(); err != nil {
-		return fmt.Errorf("failed to init default users: %v", err)
+// SeedRBAC 初始化内置角色和权限（幂等，只补充缺失项）
+func SeedRBAC() error {
+	permissions := make(map[string]models.Permission)
+	for code, description := range models.DefaultPermissions {
+		permission := models.Permission{Code: code, Description: description}
+		if err := DB.Where(models.Permission{Code: code}).FirstOrCreate(&permission).Error; err != nil {
+			return fmt.Errorf("failed to create permission %s: %v", code, err)
+		}
+		permissions[code] = permission
 	}
 
-	log.Println("Database initialized successfully")
+	for name, codes := range models.DefaultRoles {
+		var role models.Role
+		if err := DB.Preload("Permissions").Where(models.Role{Name: name}).FirstOrCreate(&role).Error; err != nil {
+			return fmt.Errorf("failed to create role %s: %v", name, err)
+		}
+
+		granted := make(map[string]bool)
+		for _, permission := range role.Permissions {
+			granted[permission.Code] = true
+		}
+
+		var missing []models.Permission
+		for _, code := range codes {
+			if !granted[code] {
+				missing = append(missing, permissions[code])
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		if err := DB.Model(&role).Association("Permissions").Append(missing); err != nil {
+			return fmt.Errorf("failed to grant permissions to role %s: %v", name, err)
+		}
+	}
+
+	return assignRolesToLegacyUsers()
+}
+
+// assignRolesToLegacyUsers 为升级前没有角色的用户分配角色：admin账号为管理员，其余为普通成员
+func assignRolesToLegacyUsers() error {
+	var users []models.User
+	if err := DB.Where("id NOT IN (?)", DB.Table("user_roles").Select("user_id")).Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		roleName := models.RoleMember
+		if user.Username == "admin" {
+			roleName = models.RoleAdmin
+		}
+		if err := AssignRoles(&user, []string{roleName}); err != nil {
+			return err
+		}
+		log.Printf("Assigned role %s to legacy user %s", roleName, user.Username)
+	}
 	return nil
 }
 
-// AutoMigrate 自动迁移数据库表
-func AutoMigrate() error {
-	return DB.AutoMigrate(
-		&models.User{},
-		&models.Image{},
-	)
+// AssignRoles 将用户的角色替换为指定角色
+func AssignRoles(user *models.User, roleNames []string) error {
+	var roles []models.Role
+	if err := DB.Where("name IN ?", roleNames).Find(&roles).Error; err != nil {
+		return err
+	}
+	if len(roles) != len(roleNames) {
+		return fmt.Errorf("unknown role in %v", roleNames)
+	}
+	return DB.Model(user).Association("Roles").Replace(roles)
 }
 
 // InitDefaultUsers 初始化默认用户
-func InitDefaultUsers() error {
+func InitDefaultUsers(hasher utils.PasswordHasher) error {
 	// 检查是否已有用户
 	var count int64
 	DB.Model(&models.User{}).Count(&count)
@@ -65,25 +151,45 @@ func InitDefaultUsers() error {
 	}
 
 	// 创建默认用户
-	defaultUsers := []models.User{
+	defaultUsers := []struct {
+		models.User
+		Role string
+	}{
 		{
-			Username: "admin",
-			Password: "admin123", // 实际项目中应该加密
-			Email:    "admin@example.com",
-			Status:   "active",
+			User: models.User{
+				Username: "admin",
+				Password: "admin123",
+				Email:    "admin@example.com",
+				Status:   "active",
+			},
+			Role: models.RoleAdmin,
 		},
 		{
-			Username: "user",
-			Password: "user123", // 实际项目中应该加密
-			Email:    "user@example.com",
-			Status:   "active",
+			User: models.User{
+				Username: "user",
+				Password: "user123",
+				Email:    "user@example.com",
+				Status:   "active",
+			},
+			Role: models.RoleMember,
 		},
 	}
 
-	for _, user := range defaultUsers {
+	for _, defaultUser := range defaultUsers {
+		user := defaultUser.User
+		hashedPassword, err := hasher.Hash(user.Password)
+		if err != nil {
+			return fmt.Errorf("failed to hash password for default user %s: %v", user.Username, err)
+		}
+		user.Password = hashedPassword
+
 		if err := DB.Create(&user).Error; err != nil {
 			return fmt.Errorf("failed to create default user %s: %v", user.Username, err)
 		}
+
+		if err := AssignRoles(&user, []string{defaultUser.Role}); err != nil {
+			return fmt.Errorf("failed to assign role to default user %s: %v", user.Username, err)
+		}
 	}
 
 	log.Println("Default users created successfully")
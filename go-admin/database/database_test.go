@@ -0,0 +1,24 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go-admin/config"
+
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB 连接临时SQLite数据库，不执行迁移
+func newTestDB(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Database = config.DatabaseConfig{Driver: config.DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db")}
+	cfg.Image.DefaultOwner = "admin"
+	if err := Connect(&cfg.Database); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	DB.Logger = logger.Discard
+	t.Cleanup(func() { Close() })
+	return cfg
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+
+	"go-admin/config"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// openDialector 根据配置创建对应数据库的驱动
+func openDialector(cfg *config.DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case config.DriverMySQL:
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			cfg.User,
+			cfg.Password,
+			cfg.Host,
+			cfg.Port,
+			cfg.DBName,
+		)
+		return mysql.Open(dsn), nil
+	case config.DriverPostgres:
+		dsn := &url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(cfg.User, cfg.Password),
+			Host:     net.JoinHostPort(cfg.Host, cfg.Port),
+			Path:     "/" + cfg.DBName,
+			RawQuery: url.Values{"sslmode": {cfg.SSLMode}}.Encode(),
+		}
+		return postgres.Open(dsn.String()), nil
+	case config.DriverSQLite:
+		// SQLite 默认不检查外键；事务开始时即获取写锁并在锁冲突时等待，
+		// 避免并发事务从读升级为写时直接返回 SQLITE_BUSY
+		dsn := cfg.Path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+		return sqlite.Open(dsn), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver: %s", cfg.Driver)
+}
+
+// Dialect 当前连接的数据库类型，取值与 config.DatabaseConfig.Driver 一致
+func Dialect() string {
+	return DB.Dialector.Name()
+}
+
+// RandomOrder 随机排序表达式，MySQL 为 RAND()，PostgreSQL 和 SQLite 为 RANDOM()
+func RandomOrder() string {
+	if Dialect() == config.DriverMySQL {
+		return "RAND()"
+	}
+	return "RANDOM()"
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"io/fs"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"go-admin/config"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+)
+
+func TestOpenDialector(t *testing.T) {
+	cfg := &config.DatabaseConfig{
+		Host:     "db.internal",
+		Port:     "5432",
+		User:     "admin",
+		Password: "p@ss/word?",
+		DBName:   "go_admin",
+		SSLMode:  "require",
+		Path:     "test.db",
+	}
+
+	for _, driver := range []string{config.DriverMySQL, config.DriverPostgres, config.DriverSQLite} {
+		cfg.Driver = driver
+		dialector, err := openDialector(cfg)
+		if err != nil {
+			t.Fatalf("openDialector(%s): %v", driver, err)
+		}
+		if dialector.Name() != driver {
+			t.Errorf("openDialector(%s).Name() = %s", driver, dialector.Name())
+		}
+
+		switch d := dialector.(type) {
+		case *mysql.Dialector:
+			if d.DSNConfig == nil || d.DSNConfig.Passwd != cfg.Password || d.DSNConfig.DBName != cfg.DBName || !d.DSNConfig.ParseTime {
+				t.Errorf("mysql DSN %q does not round-trip the config", d.DSN)
+			}
+		case *postgres.Dialector:
+			// 密码中的特殊字符经过转义
+			dsn, err := url.Parse(d.DSN)
+			if err != nil {
+				t.Fatalf("parse postgres DSN: %v", err)
+			}
+			password, _ := dsn.User.Password()
+			if password != cfg.Password || dsn.Host != "db.internal:5432" || dsn.Path != "/go_admin" || dsn.Query().Get("sslmode") != "require" {
+				t.Errorf("postgres DSN %q does not round-trip the config", d.DSN)
+			}
+		}
+	}
+
+	cfg.Driver = "oracle"
+	if _, err := openDialector(cfg); err == nil {
+		t.Error("openDialector(oracle) = nil error, want unsupported driver")
+	}
+}
+
+func TestRandomOrder(t *testing.T) {
+	newTestDB(t)
+
+	if Dialect() != config.DriverSQLite {
+		t.Fatalf("Dialect() = %s, want sqlite", Dialect())
+	}
+	if RandomOrder() != "RANDOM()" {
+		t.Fatalf("RandomOrder() = %s, want RANDOM()", RandomOrder())
+	}
+	var values []int
+	if err := DB.Raw("SELECT n FROM (SELECT 1 AS n UNION ALL SELECT 2) ORDER BY " + RandomOrder()).Scan(&values).Error; err != nil {
+		t.Fatalf("order by %s: %v", RandomOrder(), err)
+	}
+	if len(values) != 2 {
+		t.Errorf("got %v, want two rows", values)
+	}
+}
+
+func TestMigrationsMatchAcrossDialects(t *testing.T) {
+	files := func(dialect string) []string {
+		entries, err := fs.ReadDir(migrationFiles, "migrations/"+dialect)
+		if err != nil {
+			t.Fatalf("read %s migrations: %v", dialect, err)
+		}
+		names := make([]string, len(entries))
+		for i, entry := range entries {
+			names[i] = entry.Name()
+		}
+		return names
+	}
+
+	// 每个数据库都有相同版本的迁移，否则切换数据库后版本号无法对应
+	want := files(config.DriverMySQL)
+	for _, dialect := range []string{config.DriverPostgres, config.DriverSQLite} {
+		if got := files(dialect); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s migrations = %v, want %v", dialect, got, want)
+		}
+	}
+}
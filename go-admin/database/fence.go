@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-admin/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrStaleFence 防护令牌小于已见过的令牌，说明调用方已失去领导权
+var ErrStaleFence = errors.New("stale fencing token")
+
+// Fence 领导者写入时携带的防护令牌
+type Fence struct {
+	Name  string
+	Token int64
+}
+
+// Check 在事务内校验防护令牌并记录，锁定令牌记录直到事务结束，
+// 旧领导者的事务会被新领导者已提交的更大令牌拒绝
+func (f *Fence) Check(tx *gorm.DB) error {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.LeaderFence{Name: f.Name}).Error; err != nil {
+		return fmt.Errorf("failed to create fence %s: %v", f.Name, err)
+	}
+
+	var fence models.LeaderFence
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", f.Name).First(&fence).Error; err != nil {
+		return err
+	}
+	if f.Token < fence.Token {
+		return ErrStaleFence
+	}
+	if f.Token == fence.Token {
+		return nil
+	}
+	return tx.Model(&fence).Update("token", f.Token).Error
+}
+
+// FenceToken 获取选举已记录的最大防护令牌，没有记录时返回0。
+// 用作领导选举的令牌下限，Redis中的计数器重置后新令牌仍大于已记录的令牌
+func FenceToken(ctx context.Context, name string) (int64, error) {
+	var fences []models.LeaderFence
+	if err := DB.WithContext(ctx).Where("name = ?", name).Limit(1).Find(&fences).Error; err != nil {
+		return 0, err
+	}
+	if len(fences) == 0 {
+		return 0, nil
+	}
+	return fences[0].Token, nil
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-admin/models"
+
+	"gorm.io/gorm"
+)
+
+func TestFenceRejectsStaleToken(t *testing.T) {
+	cfg := newTestDB(t)
+	if _, err := MigrateUp(cfg); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	check := func(token int64) error {
+		return DB.Transaction(func(tx *gorm.DB) error {
+			fence := &Fence{Name: "cleanup", Token: token}
+			return fence.Check(tx)
+		})
+	}
+
+	if err := check(2); err != nil {
+		t.Fatalf("Check(2): %v", err)
+	}
+	if err := check(2); err != nil {
+		t.Fatalf("Check(2) again: %v", err)
+	}
+	// 旧领导者的令牌被拒绝
+	if err := check(1); !errors.Is(err, ErrStaleFence) {
+		t.Fatalf("Check(1) = %v, want ErrStaleFence", err)
+	}
+	if err := check(3); err != nil {
+		t.Fatalf("Check(3): %v", err)
+	}
+
+	var fence models.LeaderFence
+	if err := DB.First(&fence, "name = ?", "cleanup").Error; err != nil {
+		t.Fatalf("load fence: %v", err)
+	}
+	if fence.Token != 3 {
+		t.Fatalf("token = %d, want 3", fence.Token)
+	}
+}
+
+func TestFenceToken(t *testing.T) {
+	cfg := newTestDB(t)
+	if _, err := MigrateUp(cfg); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	ctx := context.Background()
+
+	if token, err := FenceToken(ctx, "cleanup"); err != nil || token != 0 {
+		t.Fatalf("FenceToken before any check = %d, %v; want 0", token, err)
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		return (&Fence{Name: "cleanup", Token: 7}).Check(tx)
+	})
+	if err != nil {
+		t.Fatalf("Check(7): %v", err)
+	}
+	if token, err := FenceToken(ctx, "cleanup"); err != nil || token != 7 {
+		t.Fatalf("FenceToken = %d, %v; want 7", token, err)
+	}
+	if token, err := FenceToken(ctx, "other"); err != nil || token != 0 {
+		t.Fatalf("FenceToken(other) = %d, %v; want 0", token, err)
+	}
+}
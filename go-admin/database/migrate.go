@@ -0,0 +1,298 @@
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+
+	"gorm.io/gorm"
+)
+
+// migrationFiles 编译进程序的迁移文件，按数据库类型分目录存放（migrations/mysql 等），
+// 文件名格式为 <版本号>_<名称>.up.sql 和 <版本号>_<名称>.down.sql，各目录的版本号保持一致。
+// 数据迁移见 dataMigrations，占用的版本号在各目录中没有对应文件
+//
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// migrationFilePattern 迁移文件名
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// baselineVersion 基线迁移的版本号，对应最早版本的表结构，不可回滚
+const baselineVersion = 1
+
+// ErrSchemaOutdated 数据库中有未执行的迁移
+var ErrSchemaOutdated = errors.New("database schema is out of date")
+
+// ErrBaselineRollback 回滚到基线之前
+var ErrBaselineRollback = errors.New("the baseline migration cannot be rolled back")
+
+// Migration 数据库迁移，SQL迁移使用 up 和 down 脚本，数据迁移使用 run
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+	run     func(tx *gorm.DB, cfg *config.Config) error
+}
+
+// MigrationStatus 迁移执行状态，Known 为 false 表示数据库中有记录但本程序中不存在该迁移
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Known     bool
+}
+
+// MigrateUp 按版本号顺序执行全部未执行的迁移，返回本次执行的迁移。
+// 数据迁移需要读取配置（如历史图片的默认归属用户）
+func MigrateUp(cfg *config.Config) ([]Migration, error) {
+	migrations, applied, err := loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := runMigration(migration, true, cfg); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// MigrateDown 按执行顺序倒序回滚最近的 steps 个迁移，返回本次回滚的迁移。
+// 基线迁移不可回滚，steps 包含基线时不回滚任何迁移并返回 ErrBaselineRollback
+func MigrateDown(steps int) ([]Migration, error) {
+	migrations, applied, err := loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int]Migration, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = migration
+	}
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	versions = versions[:min(steps, len(versions))]
+	if len(versions) > 0 && versions[len(versions)-1] <= baselineVersion {
+		return nil, fmt.Errorf("%w, at most %d migrations can be rolled back", ErrBaselineRollback, len(versions)-1)
+	}
+
+	var done []Migration
+	for _, version := range versions {
+		migration, ok := known[version]
+		if !ok {
+			return done, fmt.Errorf("migration %d (%s) is not known to this build", version, applied[version].Name)
+		}
+		if err := runMigration(migration, false, nil); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// MigrationStatuses 列出全部迁移的执行状态，按版本号排序
+func MigrationStatuses() ([]MigrationStatus, error) {
+	migrations, applied, err := loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name, Known: true}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range applied {
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, MigrationStatus{Version: record.Version, Name: record.Name, AppliedAt: &appliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// CheckMigrations 检查数据库结构是否为最新，有未执行的迁移时返回 ErrSchemaOutdated。
+// 数据库中存在本程序不认识的迁移（如回退到旧版本）时只记录日志
+func CheckMigrations() error {
+	statuses, err := MigrationStatuses()
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, status := range statuses {
+		if !status.Known {
+			log.Printf("Database has migration %d (%s) unknown to this build", status.Version, status.Name)
+		} else if status.AppliedAt == nil {
+			pending = append(pending, fmt.Sprintf("%d_%s", status.Version, status.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w, pending migrations: %s (run \"migrate up\" first)", ErrSchemaOutdated, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// loadState 读取全部迁移和已执行的迁移记录
+func loadState() ([]Migration, map[int]models.SchemaMigration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !DB.Migrator().HasTable(&models.SchemaMigration{}) {
+		if err := DB.Migrator().CreateTable(&models.SchemaMigration{}); err != nil {
+			return nil, nil, fmt.Errorf("failed to create schema_migrations table: %v", err)
+		}
+	}
+
+	var records []models.SchemaMigration
+	if err := DB.Find(&records).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to read schema_migrations: %v", err)
+	}
+	applied := make(map[int]models.SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return migrations, applied, nil
+}
+
+// loadMigrations 读取当前数据库类型的迁移文件并合并数据迁移，
+// 除基线外每个版本必须同时有 up 和 down 文件
+func loadMigrations() ([]Migration, error) {
+	dir := "migrations/" + Dialect()
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database %s: %v", Dialect(), err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		data, err := fs.ReadFile(migrationFiles, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.up = string(data)
+		} else {
+			migration.down = string(data)
+		}
+	}
+
+	for _, migration := range dataMigrations {
+		if existing, ok := byVersion[migration.Version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", migration.Version, existing.Name, migration.Name)
+		}
+		byVersion[migration.Version] = &migration
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.run != nil {
+			migrations = append(migrations, *migration)
+			continue
+		}
+		if migration.up == "" || (migration.down == "" && migration.Version != baselineVersion) {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// runMigration 在事务中执行迁移并更新迁移记录，数据迁移回滚时只删除记录。
+// PostgreSQL 和 SQLite 失败时整体回滚；MySQL 的 DDL 会隐式提交，执行到一半失败时需要根据错误手动修复后重新执行
+func runMigration(migration Migration, up bool, cfg *config.Config) error {
+	script, action := migration.up, "apply"
+	if !up {
+		script, action = migration.down, "roll back"
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if migration.run != nil && up {
+			if err := migration.run(tx, cfg); err != nil {
+				return err
+			}
+		}
+		for _, statement := range splitStatements(script) {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		if up {
+			return tx.Create(&models.SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		}
+		return tx.Delete(&models.SchemaMigration{}, migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to %s migration %d_%s: %v", action, migration.Version, migration.Name, err)
+	}
+
+	log.Printf("Migration %d_%s: %s done", migration.Version, migration.Name, action)
+	return nil
+}
+
+// splitStatements 将迁移脚本拆分为单条语句，语句以行尾的分号结束，忽略 -- 开头的注释行
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"go-admin/models"
+	"go-admin/utils"
+)
+
+// migrateBaseline 只执行基线迁移，模拟由最早版本 AutoMigrate 建表的数据库
+func migrateBaseline(t *testing.T) {
+	t.Helper()
+	migrations, _, err := loadState()
+	if err != nil {
+		t.Fatalf("loadState: %v", err)
+	}
+	if migrations[0].Version != baselineVersion {
+		t.Fatalf("first migration = %d, want baseline", migrations[0].Version)
+	}
+	if err := runMigration(migrations[0], true, nil); err != nil {
+		t.Fatalf("runMigration: %v", err)
+	}
+}
+
+func TestMigrateUpFreshDatabase(t *testing.T) {
+	cfg := newTestDB(t)
+
+	if err := CheckMigrations(); !errors.Is(err, ErrSchemaOutdated) {
+		t.Fatalf("CheckMigrations before migrate = %v, want ErrSchemaOutdated", err)
+	}
+
+	applied, err := MigrateUp(cfg)
+	if err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(migrations))
+	}
+	if err := CheckMigrations(); err != nil {
+		t.Fatalf("CheckMigrations after migrate: %v", err)
+	}
+
+	// 迁移后的表结构与模型一致
+	for _, model := range []interface{}{
+		&models.User{}, &models.Role{}, &models.Permission{}, &models.Image{},
+		&models.ImageBlob{}, &models.LeaderFence{}, &models.TaskRecord{},
+	} {
+		if !DB.Migrator().HasTable(model) {
+			t.Errorf("table for %T is missing", model)
+		}
+	}
+	for _, column := range []string{"owner_id", "mime_type", "content_hash", "width", "height", "variants"} {
+		if !DB.Migrator().HasColumn(&models.Image{}, column) {
+			t.Errorf("images.%s is missing", column)
+		}
+	}
+
+	// 再次执行没有待执行的迁移
+	if applied, err := MigrateUp(cfg); err != nil || len(applied) != 0 {
+		t.Fatalf("second MigrateUp = %d, %v; want 0, nil", len(applied), err)
+	}
+
+	if err := SeedRBAC(); err != nil {
+		t.Fatalf("SeedRBAC: %v", err)
+	}
+	if err := InitDefaultUsers(utils.NewBcryptHasher(4)); err != nil {
+		t.Fatalf("InitDefaultUsers: %v", err)
+	}
+	var admin models.User
+	if err := DB.Preload("Roles").Where("username = ?", "admin").First(&admin).Error; err != nil {
+		t.Fatalf("load admin: %v", err)
+	}
+	if len(admin.Roles) != 1 || admin.Roles[0].Name != models.RoleAdmin {
+		t.Fatalf("admin roles = %+v, want [%s]", admin.Roles, models.RoleAdmin)
+	}
+}
+
+func TestMigrateUpFromBaseline(t *testing.T) {
+	cfg := newTestDB(t)
+	migrateBaseline(t)
+
+	// 基线版本的数据：上传者为空，路径为本地文件路径
+	for _, statement := range []string{
+		"INSERT INTO users (username, password, email, status) VALUES ('admin', 'admin123', 'admin@example.com', 'active')",
+		"INSERT INTO images (image_code, file_name, file_path, file_size, file_type, expire_time, status) VALUES ('legacy', 'a.png', './uploads/images/legacy.png', 1, 'png', '2030-01-01 00:00:00', 'active')",
+	} {
+		if err := DB.Exec(statement).Error; err != nil {
+			t.Fatalf("seed baseline data: %v", err)
+		}
+	}
+
+	if _, err := MigrateUp(cfg); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	var admin models.User
+	if err := DB.Where("username = ?", "admin").First(&admin).Error; err != nil {
+		t.Fatalf("load admin: %v", err)
+	}
+	var image models.Image
+	if err := DB.Where("image_code = ?", "legacy").First(&image).Error; err != nil {
+		t.Fatalf("load image: %v", err)
+	}
+	if image.OwnerID != admin.ID {
+		t.Errorf("owner_id = %d, want %d", image.OwnerID, admin.ID)
+	}
+	if image.FilePath != "legacy.png" {
+		t.Errorf("file_path = %q, want legacy.png", image.FilePath)
+	}
+}
+
+func TestMigrateUpMissingDefaultOwner(t *testing.T) {
+	cfg := newTestDB(t)
+	migrateBaseline(t)
+	cfg.Image.DefaultOwner = "nobody"
+
+	err := DB.Exec("INSERT INTO images (image_code, file_name, file_path, file_size, file_type, expire_time) VALUES ('legacy', 'a.png', 'legacy.png', 1, 'png', '2030-01-01 00:00:00')").Error
+	if err != nil {
+		t.Fatalf("seed baseline data: %v", err)
+	}
+
+	if _, err := MigrateUp(cfg); err == nil {
+		t.Fatal("MigrateUp succeeded without the default owner")
+	}
+
+	// 失败的数据迁移整体回滚，之前的迁移保留
+	statuses, err := MigrationStatuses()
+	if err != nil {
+		t.Fatalf("MigrationStatuses: %v", err)
+	}
+	for _, status := range statuses {
+		applied := status.AppliedAt != nil
+		if want := status.Version < 4; applied != want {
+			t.Errorf("migration %d applied = %v, want %v", status.Version, applied, want)
+		}
+	}
+}
+
+func TestMigrateDown(t *testing.T) {
+	cfg := newTestDB(t)
+	applied, err := MigrateUp(cfg)
+	if err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	// 包含基线时不回滚任何迁移
+	if _, err := MigrateDown(len(applied)); !errors.Is(err, ErrBaselineRollback) {
+		t.Fatalf("MigrateDown(all) = %v, want ErrBaselineRollback", err)
+	}
+	if err := CheckMigrations(); err != nil {
+		t.Fatalf("CheckMigrations after refused rollback: %v", err)
+	}
+
+	rolledBack, err := MigrateDown(len(applied) - 1)
+	if err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if len(rolledBack) != len(applied)-1 {
+		t.Fatalf("rolled back %d migrations, want %d", len(rolledBack), len(applied)-1)
+	}
+	if DB.Migrator().HasTable(&models.Role{}) || DB.Migrator().HasColumn(&models.Image{}, "owner_id") {
+		t.Fatal("schema was not rolled back to the baseline")
+	}
+	if !DB.Migrator().HasTable(&models.Image{}) {
+		t.Fatal("baseline table images was dropped")
+	}
+
+	if _, err := MigrateUp(cfg); err != nil {
+		t.Fatalf("MigrateUp after rollback: %v", err)
+	}
+	if err := CheckMigrations(); err != nil {
+		t.Fatalf("CheckMigrations: %v", err)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- comment
+CREATE TABLE a (
+  id integer
+);
+
+-- another comment
+CREATE INDEX idx ON a (id);`
+
+	statements := splitStatements(script)
+	if len(statements) != 2 {
+		t.Fatalf("got %d statements, want 2: %q", len(statements), statements)
+	}
+	if statements[1] != "CREATE INDEX idx ON a (id);" {
+		t.Errorf("statement = %q", statements[1])
+	}
+}
@@ -0,0 +1,36 @@
+-- 角色与权限
+
+CREATE TABLE `roles` (
+  `id` bigint AUTO_INCREMENT,
+  `name` varchar(50) NOT NULL,
+  `description` varchar(255),
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_roles_name` (`name`)
+);
+
+CREATE TABLE `permissions` (
+  `id` bigint AUTO_INCREMENT,
+  `code` varchar(100) NOT NULL,
+  `description` varchar(255),
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_permissions_code` (`code`)
+);
+
+CREATE TABLE `user_roles` (
+  `user_id` bigint,
+  `role_id` bigint,
+  PRIMARY KEY (`user_id`, `role_id`),
+  CONSTRAINT `fk_user_roles_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`),
+  CONSTRAINT `fk_user_roles_role` FOREIGN KEY (`role_id`) REFERENCES `roles` (`id`)
+);
+
+CREATE TABLE `role_permissions` (
+  `role_id` bigint,
+  `permission_id` bigint,
+  PRIMARY KEY (`role_id`, `permission_id`),
+  CONSTRAINT `fk_role_permissions_role` FOREIGN KEY (`role_id`) REFERENCES `roles` (`id`),
+  CONSTRAINT `fk_role_permissions_permission` FOREIGN KEY (`permission_id`) REFERENCES `permissions` (`id`)
+);
@@ -0,0 +1,5 @@
+-- 图片上传者，历史图片为 0，由 0004 数据迁移归属到默认用户
+
+ALTER TABLE `images`
+  ADD COLUMN `owner_id` bigint NOT NULL DEFAULT 0,
+  ADD INDEX `idx_images_owner_id` (`owner_id`);
@@ -0,0 +1,6 @@
+-- 上传时检测到的MIME类型和图片尺寸
+
+ALTER TABLE `images`
+  ADD COLUMN `mime_type` varchar(50),
+  ADD COLUMN `width` bigint,
+  ADD COLUMN `height` bigint;
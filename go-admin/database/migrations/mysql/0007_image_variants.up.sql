@@ -0,0 +1,4 @@
+-- 已生成的尺寸变体
+
+ALTER TABLE `images`
+  ADD COLUMN `variants` varchar(255);
@@ -0,0 +1,5 @@
+-- 内容SHA-256，用作强ETag和去重
+
+ALTER TABLE `images`
+  ADD COLUMN `content_hash` varchar(64),
+  ADD INDEX `idx_images_content_hash` (`content_hash`);
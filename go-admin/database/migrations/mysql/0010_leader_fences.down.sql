@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `leader_fences`;
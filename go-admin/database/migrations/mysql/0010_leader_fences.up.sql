@@ -0,0 +1,8 @@
+-- 领导者选举的防护令牌
+
+CREATE TABLE `leader_fences` (
+  `name` varchar(100),
+  `token` bigint NOT NULL DEFAULT 0,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`name`)
+);
@@ -0,0 +1,23 @@
+-- 后台任务历史记录
+
+CREATE TABLE `task_records` (
+  `id` varchar(64),
+  `type` varchar(32),
+  `status` varchar(20),
+  `owner_id` bigint,
+  `image_id` bigint,
+  `payload` text,
+  `retry_count` bigint,
+  `last_error` text,
+  `next_attempt_at` datetime(3) NULL,
+  `failed_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_task_records_type` (`type`),
+  INDEX `idx_task_records_status` (`status`),
+  INDEX `idx_task_records_owner_id` (`owner_id`),
+  INDEX `idx_task_records_image_id` (`image_id`),
+  INDEX `idx_task_records_created_at` (`created_at`),
+  INDEX `idx_task_records_updated_at` (`updated_at`)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE `image_blobs`
+  DROP COLUMN `status`;
@@ -0,0 +1,31 @@
+-- 基线表结构，与最早版本 AutoMigrate 创建的表一致。
+-- 使用 IF NOT EXISTS，已由 AutoMigrate 建表的数据库执行后直接纳入版本管理，之后的结构变更由后续迁移完成
+
+CREATE TABLE IF NOT EXISTS "users" (
+  "id" bigserial,
+  "username" varchar(50) NOT NULL,
+  "password" varchar(100) NOT NULL,
+  "email" varchar(100) NOT NULL,
+  "status" varchar(20) DEFAULT 'active',
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_username" ON "users" ("username");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email");
+
+CREATE TABLE IF NOT EXISTS "images" (
+  "id" bigserial,
+  "image_code" varchar(50) NOT NULL,
+  "file_name" varchar(255) NOT NULL,
+  "file_path" varchar(500) NOT NULL,
+  "file_size" bigint NOT NULL,
+  "file_type" varchar(50) NOT NULL,
+  "upload_time" timestamptz,
+  "expire_time" timestamptz NOT NULL,
+  "status" varchar(20) DEFAULT 'active',
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_images_image_code" ON "images" ("image_code");
@@ -0,0 +1,4 @@
+ALTER TABLE "images"
+  DROP COLUMN "mime_type",
+  DROP COLUMN "width",
+  DROP COLUMN "height";
@@ -0,0 +1,15 @@
+-- 按内容去重的存储对象及引用计数
+
+CREATE TABLE "image_blobs" (
+  "id" bigserial,
+  "content_hash" varchar(64) NOT NULL,
+  "file_path" varchar(500) NOT NULL,
+  "file_size" bigint NOT NULL,
+  "mime_type" varchar(50),
+  "variants" varchar(255),
+  "ref_count" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "idx_image_blobs_content_hash" ON "image_blobs" ("content_hash");
@@ -0,0 +1,23 @@
+-- 后台任务历史记录
+
+CREATE TABLE "task_records" (
+  "id" varchar(64),
+  "type" varchar(32),
+  "status" varchar(20),
+  "owner_id" bigint,
+  "image_id" bigint,
+  "payload" text,
+  "retry_count" bigint,
+  "last_error" text,
+  "next_attempt_at" timestamptz,
+  "failed_at" timestamptz,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_task_records_type" ON "task_records" ("type");
+CREATE INDEX "idx_task_records_status" ON "task_records" ("status");
+CREATE INDEX "idx_task_records_owner_id" ON "task_records" ("owner_id");
+CREATE INDEX "idx_task_records_image_id" ON "task_records" ("image_id");
+CREATE INDEX "idx_task_records_created_at" ON "task_records" ("created_at");
+CREATE INDEX "idx_task_records_updated_at" ON "task_records" ("updated_at");
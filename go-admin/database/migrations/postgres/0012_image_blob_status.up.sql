@@ -0,0 +1,4 @@
+-- 内容上传状态：上传者在事务外写入存储，完成后标记为就绪
+
+ALTER TABLE "image_blobs"
+  ADD COLUMN "status" varchar(20) NOT NULL DEFAULT 'ready';
@@ -0,0 +1,29 @@
+-- 基线表结构，与最早版本 AutoMigrate 创建的表一致。
+-- 使用 IF NOT EXISTS，已由 AutoMigrate 建表的数据库执行后直接纳入版本管理，之后的结构变更由后续迁移完成
+
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `username` text NOT NULL,
+  `password` text NOT NULL,
+  `email` text NOT NULL,
+  `status` text DEFAULT 'active',
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_users_username` ON `users` (`username`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_users_email` ON `users` (`email`);
+
+CREATE TABLE IF NOT EXISTS `images` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `image_code` text NOT NULL,
+  `file_name` text NOT NULL,
+  `file_path` text NOT NULL,
+  `file_size` integer NOT NULL,
+  `file_type` text NOT NULL,
+  `upload_time` datetime,
+  `expire_time` datetime NOT NULL,
+  `status` text DEFAULT 'active',
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_images_image_code` ON `images` (`image_code`);
@@ -0,0 +1,34 @@
+-- 角色与权限
+
+CREATE TABLE `roles` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `name` text NOT NULL,
+  `description` text,
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE UNIQUE INDEX `idx_roles_name` ON `roles` (`name`);
+
+CREATE TABLE `permissions` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `code` text NOT NULL,
+  `description` text,
+  `created_at` datetime
+);
+CREATE UNIQUE INDEX `idx_permissions_code` ON `permissions` (`code`);
+
+CREATE TABLE `user_roles` (
+  `user_id` integer,
+  `role_id` integer,
+  PRIMARY KEY (`user_id`, `role_id`),
+  CONSTRAINT `fk_user_roles_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`),
+  CONSTRAINT `fk_user_roles_role` FOREIGN KEY (`role_id`) REFERENCES `roles` (`id`)
+);
+
+CREATE TABLE `role_permissions` (
+  `role_id` integer,
+  `permission_id` integer,
+  PRIMARY KEY (`role_id`, `permission_id`),
+  CONSTRAINT `fk_role_permissions_role` FOREIGN KEY (`role_id`) REFERENCES `roles` (`id`),
+  CONSTRAINT `fk_role_permissions_permission` FOREIGN KEY (`permission_id`) REFERENCES `permissions` (`id`)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS `idx_images_owner_id`;
+ALTER TABLE `images` DROP COLUMN `owner_id`;
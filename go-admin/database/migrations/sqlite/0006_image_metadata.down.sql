@@ -0,0 +1,3 @@
+ALTER TABLE `images` DROP COLUMN `mime_type`;
+ALTER TABLE `images` DROP COLUMN `width`;
+ALTER TABLE `images` DROP COLUMN `height`;
@@ -0,0 +1,5 @@
+-- 上传时检测到的MIME类型和图片尺寸
+
+ALTER TABLE `images` ADD COLUMN `mime_type` text;
+ALTER TABLE `images` ADD COLUMN `width` integer;
+ALTER TABLE `images` ADD COLUMN `height` integer;
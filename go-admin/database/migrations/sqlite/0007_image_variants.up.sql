@@ -0,0 +1,3 @@
+-- 已生成的尺寸变体
+
+ALTER TABLE `images` ADD COLUMN `variants` text;
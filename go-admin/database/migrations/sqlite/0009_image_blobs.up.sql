@@ -0,0 +1,14 @@
+-- 按内容去重的存储对象及引用计数
+
+CREATE TABLE `image_blobs` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `content_hash` text NOT NULL,
+  `file_path` text NOT NULL,
+  `file_size` integer NOT NULL,
+  `mime_type` text,
+  `variants` text,
+  `ref_count` integer NOT NULL DEFAULT 0,
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE UNIQUE INDEX `idx_image_blobs_content_hash` ON `image_blobs` (`content_hash`);
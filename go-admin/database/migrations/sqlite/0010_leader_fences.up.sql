@@ -0,0 +1,8 @@
+-- 领导者选举的防护令牌
+
+CREATE TABLE `leader_fences` (
+  `name` text,
+  `token` integer NOT NULL DEFAULT 0,
+  `updated_at` datetime,
+  PRIMARY KEY (`name`)
+);
@@ -0,0 +1,23 @@
+-- 后台任务历史记录
+
+CREATE TABLE `task_records` (
+  `id` text,
+  `type` text,
+  `status` text,
+  `owner_id` integer,
+  `image_id` integer,
+  `payload` text,
+  `retry_count` integer,
+  `last_error` text,
+  `next_attempt_at` datetime,
+  `failed_at` datetime,
+  `created_at` datetime,
+  `updated_at` datetime,
+  PRIMARY KEY (`id`)
+);
+CREATE INDEX `idx_task_records_type` ON `task_records` (`type`);
+CREATE INDEX `idx_task_records_status` ON `task_records` (`status`);
+CREATE INDEX `idx_task_records_owner_id` ON `task_records` (`owner_id`);
+CREATE INDEX `idx_task_records_image_id` ON `task_records` (`image_id`);
+CREATE INDEX `idx_task_records_created_at` ON `task_records` (`created_at`);
+CREATE INDEX `idx_task_records_updated_at` ON `task_records` (`updated_at`);
@@ -0,0 +1 @@
+ALTER TABLE `image_blobs` DROP COLUMN `status`;
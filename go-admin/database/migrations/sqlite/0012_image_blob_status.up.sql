@@ -0,0 +1,3 @@
+-- 内容上传状态：上传者在事务外写入存储，完成后标记为就绪
+
+ALTER TABLE `image_blobs` ADD COLUMN `status` text NOT NULL DEFAULT 'ready';
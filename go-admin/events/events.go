@@ -0,0 +1,144 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// subscriberBuffer 每个订阅者的缓冲区大小，缓冲区满时丢弃事件，避免慢客户端阻塞其他订阅者
+const subscriberBuffer = 64
+
+// Publish 发布事件，失败时只记录日志，事件推送不影响业务流程
+func Publish(ctx context.Context, redisClient *redis.Client, event *models.Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal %s event: %v", event.Type, err)
+		return
+	}
+	if err := redisClient.Publish(ctx, config.EventChannel, data).Err(); err != nil {
+		log.Printf("Failed to publish %s event: %v", event.Type, err)
+	}
+}
+
+// Filter 事件过滤函数，返回true时推送给订阅者
+type Filter func(event *models.Event) bool
+
+// subscriber 订阅者
+type subscriber struct {
+	events chan models.Event
+	filter Filter
+}
+
+// Hub 每个进程只订阅一次Redis频道，再分发给本进程的全部订阅者
+type Hub struct {
+	redisClient *redis.Client
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+// NewHub 创建事件分发器
+func NewHub(redisClient *redis.Client) *Hub {
+	return &Hub{
+		redisClient: redisClient,
+		subscribers: make(map[*subscriber]struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Start 订阅Redis事件频道，连接断开时由go-redis自动重连
+func (h *Hub) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	pubsub := h.redisClient.Subscribe(ctx, config.EventChannel)
+
+	go func() {
+		defer close(h.done)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				var event models.Event
+				if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+					log.Printf("Error unmarshaling event: %v", err)
+					continue
+				}
+				h.broadcast(&event)
+			}
+		}
+	}()
+}
+
+// Stop 停止订阅并关闭全部订阅者
+func (h *Hub) Stop() {
+	if h.cancel == nil {
+		return
+	}
+	h.cancel()
+	<-h.done
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		close(sub.events)
+		delete(h.subscribers, sub)
+	}
+}
+
+// Subscribe 订阅事件，返回的函数用于取消订阅
+func (h *Hub) Subscribe(filter Filter) (<-chan models.Event, func()) {
+	sub := &subscriber{
+		events: make(chan models.Event, subscriberBuffer),
+		filter: filter,
+	}
+
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[sub]; ok {
+			close(sub.events)
+			delete(h.subscribers, sub)
+		}
+	}
+	return sub.events, unsubscribe
+}
+
+// broadcast 分发事件
+func (h *Hub) broadcast(event *models.Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.events <- *event:
+		default:
+			// 订阅者处理不过来，丢弃事件
+		}
+	}
+}
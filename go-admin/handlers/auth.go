@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"errors"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 	"go-admin/models"
 	"go-admin/utils"
@@ -35,8 +38,15 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// 加载角色与权限
+	principal, err := h.userService.LoadPrincipal(user.ID)
+	if err != nil {
+		utils.Unauthorized(c, err.Error())
+		return
+	}
+
 	// 生成JWT token
-	token, err := h.jwtManager.GenerateToken(user.ID, user.Username)
+	tokens, err := h.jwtManager.GenerateTokenPair(principal)
 	if err != nil {
 		utils.InternalServerError(c, "Failed to generate token")
 		return
@@ -44,13 +54,75 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	// 返回登录响应
 	response := models.LoginResponse{
-		Token: token,
-		User:  user.ToResponse(),
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         user.ToResponse(),
 	}
 
 	utils.SuccessWithMessage(c, "Login successful", response)
 }
 
+// Refresh 使用刷新令牌换取新的令牌对
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	tokens, err := h.jwtManager.Refresh(req.RefreshToken, h.userService.LoadPrincipal)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidRefreshToken) || errors.Is(err, utils.ErrRefreshTokenReused) {
+			utils.Unauthorized(c, err.Error())
+		} else {
+			utils.InternalServerError(c, "Failed to refresh token")
+		}
+		return
+	}
+
+	utils.SuccessWithMessage(c, "Token refreshed successfully", tokens)
+}
+
+// Logout 退出登录，吊销当前访问令牌及其会话
+func (h *AuthHandler) Logout(c *gin.Context) {
+	claims := c.MustGet("claims").(*utils.Claims)
+
+	if err := h.jwtManager.RevokeToken(claims); err != nil {
+		utils.InternalServerError(c, "Failed to revoke token")
+		return
+	}
+
+	if err := h.jwtManager.RevokeSession(claims.SessionID); err != nil {
+		utils.InternalServerError(c, "Failed to revoke session")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "Logout successful", nil)
+}
+
+// RevokeUserSessions 吊销指定用户的全部会话
+func (h *AuthHandler) RevokeUserSessions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	if _, err := h.userService.GetByID(id); err != nil {
+		utils.NotFound(c, "User not found")
+		return
+	}
+
+	if err := h.jwtManager.RevokeAllSessions(id); err != nil {
+		utils.InternalServerError(c, "Failed to revoke sessions")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "User sessions revoked successfully", nil)
+}
+
 // GetProfile 获取当前用户信息
 func (h *AuthHandler) GetProfile(c *gin.Context) {
 	userID := c.GetInt("user_id")
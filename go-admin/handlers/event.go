@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"go-admin/events"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventHeartbeatInterval 心跳间隔，防止代理因连接空闲而断开
+const eventHeartbeatInterval = 15 * time.Second
+
+// eventSessionCheckInterval 连接期间检查会话是否被吊销的间隔
+const eventSessionCheckInterval = 30 * time.Second
+
+// EventHandler 实时事件处理器
+type EventHandler struct {
+	hub                  *events.Hub
+	jwtManager           *utils.JWTManager
+	heartbeatInterval    time.Duration
+	sessionCheckInterval time.Duration
+}
+
+// NewEventHandler 创建实时事件处理器
+func NewEventHandler(hub *events.Hub, jwtManager *utils.JWTManager) *EventHandler {
+	return &EventHandler{
+		hub:                  hub,
+		jwtManager:           jwtManager,
+		heartbeatInterval:    eventHeartbeatInterval,
+		sessionCheckInterval: eventSessionCheckInterval,
+	}
+}
+
+// Ticket 签发一次性的实时事件连接票据，有效期 utils.StreamTicketTTL
+func (h *EventHandler) Ticket(c *gin.Context) {
+	claims := c.MustGet("claims").(*utils.Claims)
+	ticket, err := h.jwtManager.IssueStreamTicket(claims)
+	if err != nil {
+		utils.InternalServerError(c, "Failed to issue stream ticket")
+		return
+	}
+	utils.Success(c, gin.H{
+		"ticket":     ticket,
+		"expires_in": int64(utils.StreamTicketTTL.Seconds()),
+	})
+}
+
+// Stream 通过Server-Sent Events推送任务进度和图片变更，管理员接收全部事件，其他用户只接收自己的事件。
+// 访问令牌过期或会话被吊销时发送 stream.closed 事件后关闭连接，客户端需重新获取票据
+func (h *EventHandler) Stream(c *gin.Context) {
+	claims := c.MustGet("claims").(*utils.Claims)
+	requester := requesterFromContext(c)
+	stream, unsubscribe := h.hub.Subscribe(func(event *models.Event) bool {
+		return requester.IsAdmin || event.UserID == requester.UserID
+	})
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭Nginx缓冲
+
+	heartbeat := time.NewTicker(h.heartbeatInterval)
+	defer heartbeat.Stop()
+	sessionCheck := time.NewTicker(h.sessionCheckInterval)
+	defer sessionCheck.Stop()
+	expired := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+	defer expired.Stop()
+
+	closeStream := func(message string) bool {
+		c.SSEvent(models.EventStreamClosed, &models.Event{
+			Type:    models.EventStreamClosed,
+			UserID:  claims.UserID,
+			Message: message,
+			Time:    time.Now(),
+		})
+		return false
+	}
+
+	// 先发送一条注释，让客户端立即确认连接已建立
+	io.WriteString(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-stream:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-heartbeat.C:
+			io.WriteString(w, ": heartbeat\n\n")
+			return true
+		case <-expired.C:
+			return closeStream("access token expired")
+		case <-sessionCheck.C:
+			err := h.jwtManager.CheckSession(claims)
+			if errors.Is(err, utils.ErrTokenRevoked) {
+				return closeStream("session revoked")
+			}
+			if err != nil {
+				log.Printf("Failed to check event stream session: %v", err)
+			}
+			return true
+		}
+	})
+}
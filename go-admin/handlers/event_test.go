@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-admin/config"
+	"go-admin/events"
+	"go-admin/models"
+	"go-admin/testutil"
+	"go-admin/utils"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// testEventServer 挂载实时事件接口的测试服务器
+type testEventServer struct {
+	handler     *EventHandler
+	jwtManager  *utils.JWTManager
+	redisServer *miniredis.Miniredis
+	redisClient *redis.Client
+	url         string
+}
+
+// newTestEventServer 启动事件分发和测试服务器，连接的身份取自 claims 中以查询参数 as 为键的声明
+func newTestEventServer(t *testing.T, claims map[string]*utils.Claims) *testEventServer {
+	t.Helper()
+	redisServer, client := testutil.NewRedis(t)
+	jwtManager := utils.NewJWTManager("test-secret", 15*time.Minute, time.Hour, client)
+
+	hub := events.NewHub(client)
+	hub.Start()
+	t.Cleanup(hub.Stop)
+	// 等待订阅生效，之后发布的事件不会丢失
+	for redisServer.PubSubNumSub(config.EventChannel)[config.EventChannel] == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	handler := NewEventHandler(hub, jwtManager)
+	router := gin.New()
+	router.GET("/events", func(c *gin.Context) {
+		c.Set("claims", claims[c.Query("as")])
+		c.Next()
+	}, handler.Stream)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return &testEventServer{
+		handler:     handler,
+		jwtManager:  jwtManager,
+		redisServer: redisServer,
+		redisClient: client,
+		url:         server.URL + "/events",
+	}
+}
+
+// sseEvent 收到的事件
+type sseEvent struct {
+	name string
+	data models.Event
+}
+
+// connect 以 as 的身份连接事件流，返回收到的事件，服务端关闭连接后通道关闭
+func (s *testEventServer) connect(t *testing.T, as string) <-chan sseEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"?as="+as, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		resp.Body.Close()
+	})
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	if line, err := reader.ReadString('\n'); err != nil || line != ": connected\n" {
+		t.Fatalf("first line = %q, %v", line, err)
+	}
+
+	received := make(chan sseEvent, 16)
+	go func() {
+		defer close(received)
+		var event sseEvent
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case strings.HasPrefix(line, "event:"):
+				event.name = strings.TrimPrefix(line, "event:")
+			case strings.HasPrefix(line, "data:"):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &event.data)
+			case line == "" && event.name != "":
+				received <- event
+				event = sseEvent{}
+			}
+		}
+	}()
+	return received
+}
+
+// next 等待下一个事件
+func next(t *testing.T, received <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case event, ok := <-received:
+		if !ok {
+			t.Fatal("stream closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+	return sseEvent{}
+}
+
+// expectClosed 等待服务端关闭连接
+func expectClosed(t *testing.T, received <-chan sseEvent) {
+	t.Helper()
+	select {
+	case event, ok := <-received:
+		if ok {
+			t.Fatalf("received %+v, want the stream to be closed", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream was not closed")
+	}
+}
+
+// testClaims 在 ttl 后过期的声明
+func testClaims(userID int, ttl time.Duration, roles ...string) *utils.Claims {
+	return &utils.Claims{
+		UserID: userID,
+		Roles:  roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+	}
+}
+
+func TestEventStreamFiltersByUser(t *testing.T) {
+	s := newTestEventServer(t, map[string]*utils.Claims{
+		"user":  testClaims(1, time.Hour),
+		"admin": testClaims(9, time.Hour, models.RoleAdmin),
+	})
+	user := s.connect(t, "user")
+	admin := s.connect(t, "admin")
+
+	ctx := context.Background()
+	events.Publish(ctx, s.redisClient, &models.Event{Type: models.EventImageUploaded, UserID: 2, ImageID: 20})
+	events.Publish(ctx, s.redisClient, &models.Event{Type: models.EventImageUploaded, UserID: 1, ImageID: 10})
+
+	// 普通用户只收到自己的事件
+	if event := next(t, user); event.name != models.EventImageUploaded || event.data.ImageID != 10 {
+		t.Fatalf("user received %+v, want image 10", event)
+	}
+	// 管理员收到全部事件
+	for _, want := range []int{20, 10} {
+		if event := next(t, admin); event.data.ImageID != want {
+			t.Fatalf("admin received %+v, want image %d", event, want)
+		}
+	}
+}
+
+func TestEventStreamClosesOnSessionRevoke(t *testing.T) {
+	claims := map[string]*utils.Claims{}
+	s := newTestEventServer(t, claims)
+	s.handler.sessionCheckInterval = 20 * time.Millisecond
+
+	tokens, err := s.jwtManager.GenerateTokenPair(&utils.Principal{UserID: 3, Username: "user"})
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+	if claims["user"], err = s.jwtManager.ValidateToken(tokens.AccessToken); err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	received := s.connect(t, "user")
+
+	// 会话有效时连接保持
+	select {
+	case event := <-received:
+		t.Fatalf("received %+v before the session was revoked", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := s.jwtManager.RevokeAllSessions(3); err != nil {
+		t.Fatalf("RevokeAllSessions: %v", err)
+	}
+	event := next(t, received)
+	if event.name != models.EventStreamClosed || event.data.Message != "session revoked" {
+		t.Fatalf("received %+v, want stream.closed for the revoked session", event)
+	}
+	expectClosed(t, received)
+}
+
+func TestEventStreamClosesOnTokenExpiry(t *testing.T) {
+	s := newTestEventServer(t, map[string]*utils.Claims{
+		"user": testClaims(1, time.Second),
+	})
+	received := s.connect(t, "user")
+
+	event := next(t, received)
+	if event.name != models.EventStreamClosed || event.data.Message != "access token expired" {
+		t.Fatalf("received %+v, want stream.closed for the expired token", event)
+	}
+	expectClosed(t, received)
+}
@@ -1,20 +1,28 @@
 package handlers
 
 import (
-	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"mime"
+	"net/http"
+	"path"
 	"strconv"
+	"strings"
 	"time"
 
-	"go-admin/config"
+	"go-admin/imaging"
 	"go-admin/models"
+	"go-admin/storage"
 	"go-admin/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
 )
 
+// maxCacheAge 浏览器缓存时间上限（1年）
+const maxCacheAge = 365 * 24 * 60 * 60
+
 // ImageHandler 图片处理器
 type ImageHandler struct {
 	imageService ImageService
@@ -79,7 +87,7 @@ func (h *ImageHandler) UploadImage(c *gin.Context) {
 	}
 
 	// 上传图片
-	image, err := h.imageService.UploadImage(file, expireValue, expireUnit)
+	image, err := h.imageService.UploadImage(file, expireValue, expireUnit, c.GetInt("user_id"))
 	if err != nil {
 		utils.BadRequest(c, err.Error())
 		return
@@ -97,11 +105,15 @@ func (h *ImageHandler) GetImage(c *gin.Context) {
 		return
 	}
 
-	image, err := h.imageService.GetImageByID(id)
-	if err != nil {
+	image, err := h.imageService.GetImageByID(id, requesterFromContext(c))
+	if errors.Is(err, ErrImageNotFound) {
 		utils.NotFound(c, "图片不存在")
 		return
 	}
+	if err != nil {
+		utils.InternalServerError(c, "获取图片信息失败")
+		return
+	}
 
 	utils.SuccessWithMessage(c, "获取图片信息成功", image.ToResponse())
 }
@@ -115,12 +127,16 @@ func (h *ImageHandler) GetImageByCode(c *gin.Context) {
 	}
 
 	image, err := h.imageService.GetImageByCode(imageCode)
-	if err != nil {
+	if errors.Is(err, ErrImageNotFound) {
 		utils.NotFound(c, "图片不存在")
 		return
 	}
+	if err != nil {
+		utils.InternalServerError(c, "获取图片信息失败")
+		return
+	}
 
-	utils.SuccessWithMessage(c, "获取图片信息成功", image.ToResponse())
+	utils.SuccessWithMessage(c, "获取图片信息成功", image.ToPublicResponse()) // 公开接口，不暴露上传者和存储信息
 }
 
 // GetImages 获取图片列表
@@ -140,7 +156,7 @@ func (h *ImageHandler) GetImages(c *gin.Context) {
 	}
 
 	// 获取图片列表
-	result, err := h.imageService.GetAllImages(page, pageSize)
+	result, err := h.imageService.GetAllImages(page, pageSize, requesterFromContext(c))
 	if err != nil {
 		utils.InternalServerError(c, "获取图片列表失败")
 		return
@@ -158,12 +174,24 @@ func (h *ImageHandler) DeleteImage(c *gin.Context) {
 		return
 	}
 
-	if err := h.imageService.DeleteImage(id); err != nil {
-		utils.BadRequest(c, err.Error())
+	task, err := h.imageService.DeleteImage(id, requesterFromContext(c))
+	if errors.Is(err, ErrImageNotFound) {
+		utils.NotFound(c, "图片不存在")
+		return
+	}
+	if errors.Is(err, ErrImageForbidden) {
+		utils.Forbidden(c, "无权删除该图片")
+		return
+	}
+	if err != nil {
+		utils.InternalServerError(c, "提交删除任务失败")
 		return
 	}
 
-	utils.SuccessWithMessage(c, "图片删除成功", nil)
+	utils.Accepted(c, "图片删除任务已提交", gin.H{
+		"task_id": task.ID,
+		"status":  task.Status,
+	})
 }
 
 // ServeImage 提供图片文件服务
@@ -175,20 +203,114 @@ func (h *ImageHandler) ServeImage(c *gin.Context) {
 	}
 
 	image, err := h.imageService.GetImageByCode(imageCode)
-	if err != nil {
+	if errors.Is(err, ErrImageNotFound) {
 		utils.NotFound(c, "图片不存在")
 		return
 	}
+	if err != nil {
+		utils.InternalServerError(c, "获取图片信息失败")
+		return
+	}
 
-	// 检查图片是否过期
-	response := image.ToResponse()
-	if response.IsExpired {
-		utils.BadRequest(c, "图片已过期")
+	// 检查图片是否过期，过期的图片文件已经或即将被清理
+	if image.Status != "active" || image.ToResponse().IsExpired {
+		utils.Gone(c, "图片已过期")
 		return
 	}
 
-	// 提供文件下载
-	c.File(image.FilePath)
+	// 从存储后端读取文件，ServeContent负责处理Range请求
+	var object storage.Object
+	if opts, ok, parseErr := parseTransformOptions(c); parseErr != nil {
+		err = parseErr
+	} else if ok {
+		if c.Query("size") != "" {
+			utils.BadRequest(c, "size 参数不能与变换参数同时使用")
+			return
+		}
+		object, err = h.imageService.OpenTransformed(image, opts)
+	} else {
+		object, err = h.imageService.OpenImageFile(image, c.Query("size"))
+	}
+	if err != nil {
+		if errors.Is(err, ErrUnknownVariant) {
+			utils.BadRequest(c, "无效的图片尺寸")
+		} else if errors.Is(err, imaging.ErrInvalidTransform) {
+			utils.BadRequest(c, err.Error())
+		} else if errors.Is(err, storage.ErrNotFound) {
+			utils.NotFound(c, "图片文件不存在")
+		} else {
+			utils.InternalServerError(c, "读取图片文件失败")
+		}
+		return
+	}
+	defer object.Close()
+
+	info := object.Info()
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = image.MimeType
+	}
+	if contentType != "" {
+		c.Header("Content-Type", contentType)
+	}
+	setCacheHeaders(c, image, info.Key)
+
+	// 图片内容在有效期内不可变，以上传时间作为Last-Modified；
+	// ServeContent负责If-None-Match/If-Modified-Since的304以及Range请求
+	http.ServeContent(c.Writer, c.Request, "", image.UploadTime, object)
+}
+
+// setCacheHeaders 设置缓存相关响应头，max-age不超过图片剩余有效期
+func setCacheHeaders(c *gin.Context, image *models.Image, key string) {
+	if etag := imageETag(image, key); etag != "" {
+		c.Header("ETag", etag)
+	}
+
+	maxAge := int64(time.Until(image.ExpireTime).Seconds())
+	if maxAge > maxCacheAge {
+		maxAge = maxCacheAge
+	}
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", max(maxAge, 0)))
+	c.Header("Expires", image.ExpireTime.UTC().Format(http.TimeFormat))
+
+	// 使用原始文件名，变换后格式改变时同步替换扩展名
+	fileName := image.FileName
+	if ext := path.Ext(key); ext != "" && !strings.EqualFold(ext, path.Ext(fileName)) {
+		fileName = strings.TrimSuffix(fileName, path.Ext(fileName)) + ext
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": fileName}))
+}
+
+// imageETag 基于内容哈希生成强ETag，变体和变换结果附加存储键摘要以区分
+func imageETag(image *models.Image, key string) string {
+	if image.ContentHash == "" {
+		return ""
+	}
+	if key == image.FilePath {
+		return `"` + image.ContentHash + `"`
+	}
+	sum := sha256.Sum256([]byte(key))
+	return `"` + image.ContentHash + "-" + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// parseTransformOptions 解析图片变换参数，未指定任何变换参数时ok为false
+func parseTransformOptions(c *gin.Context) (opts imaging.TransformOptions, ok bool, err error) {
+	opts.Fit = c.Query("fit")
+	opts.Format = c.Query("format")
+	ok = opts.Fit != "" || opts.Format != ""
+
+	for name, target := range map[string]*int{"w": &opts.Width, "h": &opts.Height, "quality": &opts.Quality} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		ok = true
+		if *target, err = strconv.Atoi(value); err != nil {
+			return opts, ok, fmt.Errorf("%w: %s must be an integer", imaging.ErrInvalidTransform, name)
+		}
+	}
+
+	return opts, ok, nil
 }
 
 // GetRandomImage 随机获取图片
@@ -220,32 +342,11 @@ func (h *ImageHandler) GetRandomImage(c *gin.Context) {
 	utils.SuccessWithMessage(c, "获取随机图片成功", response)
 }
 
-// GetTaskStatus 获取任务状态
-func (h *ImageHandler) GetTaskStatus(c *gin.Context) {
-	taskID := c.Param("taskId")
-	if taskID == "" {
-		utils.BadRequest(c, "任务ID不能为空")
-		return
+// requesterFromContext 从认证信息中获取调用者身份
+func requesterFromContext(c *gin.Context) Requester {
+	claims := c.MustGet("claims").(*utils.Claims)
+	return Requester{
+		UserID:  claims.UserID,
+		IsAdmin: claims.HasRole(models.RoleAdmin),
 	}
-
-	// 从Redis获取任务状态
-	ctx := context.Background()
-	statusKey := fmt.Sprintf("task:status:%s", taskID)
-	taskJSON, err := config.RedisClient.Get(ctx, statusKey).Result()
-	if err != nil {
-		if err == redis.Nil {
-			utils.NotFound(c, "任务不存在")
-		} else {
-			utils.InternalServerError(c, "获取任务状态失败")
-		}
-		return
-	}
-
-	var task models.DeleteTask
-	if err := json.Unmarshal([]byte(taskJSON), &task); err != nil {
-		utils.InternalServerError(c, "解析任务状态失败")
-		return
-	}
-
-	utils.SuccessWithMessage(c, "获取任务状态成功", task)
 }
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"go-admin/database"
+	"go-admin/imaging"
+	"go-admin/models"
+	"go-admin/storage"
+	"go-admin/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 内容记录状态
+const (
+	blobPending = "pending" // 上传者正在写入存储
+	blobReady   = "ready"   // 原图和变体已就绪
+	blobFailed  = "failed"  // 上传失败，等待引用该内容的其他上传接手
+)
+
+const (
+	// blobClaimTimeout 上传中的内容超过该时间仍未就绪时视为上传者已退出，允许其他上传接手
+	blobClaimTimeout = 2 * time.Minute
+	// blobPollInterval 等待其他上传完成时的轮询间隔
+	blobPollInterval = 50 * time.Millisecond
+)
+
+// acquireBlob 登记一次对内容的引用。内容首次出现时插入上传中的记录，
+// 插入成功的调用方负责上传文件（claimed为true），其他调用方只增加引用计数
+func acquireBlob(contentHash string, info *imaging.Info, size int64) (blob *models.ImageBlob, claimed bool, err error) {
+	for {
+		candidate := models.ImageBlob{
+			ContentHash: contentHash,
+			FilePath:    models.BlobKey(contentHash, strings.ToLower(utils.NewULID()), imaging.Extension(info.Format)),
+			FileSize:    size,
+			MimeType:    info.MimeType,
+			RefCount:    1,
+			Status:      blobPending,
+		}
+		result := database.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "content_hash"}},
+			DoNothing: true,
+		}).Create(&candidate)
+		if result.Error != nil {
+			return nil, false, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return &candidate, true, nil
+		}
+
+		// 内容已存在时增加引用；不更新updated_at，它记录的是上传者认领的时间
+		result = database.DB.Model(&models.ImageBlob{}).Where("content_hash = ?", contentHash).
+			UpdateColumn("ref_count", gorm.Expr("ref_count + 1"))
+		if result.Error != nil {
+			return nil, false, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue // 记录恰好随最后一个引用被删除，重新插入
+		}
+
+		var stored models.ImageBlob
+		if err := database.DB.Where("content_hash = ?", contentHash).First(&stored).Error; err != nil {
+			return nil, false, err
+		}
+		return &stored, false, nil
+	}
+}
+
+// claimBlob 接手上传失败或超时未完成的内容，返回是否认领成功
+func claimBlob(blob *models.ImageBlob) (bool, error) {
+	result := database.DB.Model(&models.ImageBlob{}).
+		Where("id = ? AND (status = ? OR (status = ? AND updated_at < ?))",
+			blob.ID, blobFailed, blobPending, time.Now().Add(-blobClaimTimeout)).
+		Updates(map[string]interface{}{"status": blobPending, "updated_at": time.Now()})
+	return result.RowsAffected == 1, result.Error
+}
+
+// markBlob 更新内容记录的上传状态
+func markBlob(blob *models.ImageBlob, updates map[string]interface{}) error {
+	return database.DB.Model(&models.ImageBlob{}).Where("id = ?", blob.ID).Updates(updates).Error
+}
+
+// releaseBlob 在事务内释放一次对内容的引用，最后一个引用释放时删除记录并返回需要删除的文件。
+// 文件由调用方在事务提交后删除。内容没有对应记录（去重之前上传的图片）时found为false
+func releaseBlob(tx *gorm.DB, contentHash string) (orphaned []string, found bool, err error) {
+	var blob models.ImageBlob
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("content_hash = ?", contentHash).First(&blob).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if blob.RefCount > 1 {
+		return nil, true, tx.Model(&blob).UpdateColumn("ref_count", gorm.Expr("ref_count - 1")).Error
+	}
+
+	if err := tx.Delete(&blob).Error; err != nil {
+		return nil, true, err
+	}
+	return storedFiles(blob.FilePath, blob.VariantPaths()), true, nil
+}
+
+// releaseImage 释放图片占用的文件：remove为true时删除记录，否则标记为过期。
+// 只有active状态的图片持有内容引用，重复执行是安全的。
+// fence不为空时先校验防护令牌，已失去领导权的调用方不会修改任何数据。
+// 事务只持有行锁，不再被引用的文件在提交之后删除，事务回滚时文件保持不变
+func releaseImage(ctx context.Context, backend storage.Backend, imageID int, remove bool, fence *database.Fence) error {
+	var orphaned []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if fence != nil {
+			if err := fence.Check(tx); err != nil {
+				return err
+			}
+		}
+
+		var image models.Image
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&image, imageID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // 已经处理过
+		}
+		if err != nil {
+			return err
+		}
+
+		if image.Status == "active" {
+			found := false
+			if image.ContentHash != "" {
+				if orphaned, found, err = releaseBlob(tx, image.ContentHash); err != nil {
+					return err
+				}
+			}
+			// 去重之前上传的图片独占自己的文件
+			if !found {
+				orphaned = storedFiles(image.FilePath, image.VariantPaths())
+			}
+		}
+
+		if remove {
+			return tx.Delete(&image).Error
+		}
+		return tx.Model(&image).Update("status", "expired").Error
+	})
+	if err != nil {
+		return err
+	}
+
+	deleteStoredFiles(ctx, backend, orphaned)
+	return nil
+}
+
+// storedFiles 原图及其全部变体的存储键，变体在前
+func storedFiles(filePath string, variantPaths []string) []string {
+	return append(variantPaths, filePath)
+}
+
+// deleteStoredFiles 删除不再被引用的文件。记录已经提交，删除失败只会遗留无人引用的文件，记录日志后继续
+func deleteStoredFiles(ctx context.Context, backend storage.Backend, keys []string) {
+	for _, key := range keys {
+		if err := backend.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete orphaned file %s: %v", key, err)
+		}
+	}
+}
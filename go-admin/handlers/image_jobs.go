@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"go-admin/config"
+	"go-admin/events"
+	"go-admin/jobs"
+	"go-admin/models"
+	"go-admin/storage"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 图片任务类型
+const (
+	JobImageDelete = "delete" // 删除图片
+	JobImageExpire = "expire" // 图片过期
+)
+
+// expiryRetryDelay 生成过期任务失败后的重试间隔
+const expiryRetryDelay = 5 * time.Second
+
+// imageJobs 图片删除和过期任务
+type imageJobs struct {
+	redisClient  *redis.Client
+	imageService ImageService
+	storage      storage.Backend
+}
+
+// RegisterImageJobs 注册图片删除和过期任务，以及按过期时间表生成过期任务的周期任务
+func RegisterImageJobs(registry *jobs.Registry, redisClient *redis.Client, imageService ImageService, backend storage.Backend) {
+	j := &imageJobs{
+		redisClient:  redisClient,
+		imageService: imageService,
+		storage:      backend,
+	}
+
+	registry.Register(jobs.Definition{
+		Type:    JobImageDelete,
+		Queue:   config.ImageDeleteQueue,
+		Channel: config.ImageDeleteChannel,
+		Handler: j.handleDelete,
+	})
+	registry.Register(jobs.Definition{
+		Type:    JobImageExpire,
+		Queue:   config.ImageExpireQueue,
+		Channel: config.ImageDeleteChannel,
+		Handler: j.handleExpire,
+	})
+
+	// 每秒检查一次过期时间表
+	registry.Every("image-expiry", 1*time.Second, j.enqueueExpiredImages)
+}
+
+// handleDelete 处理删除任务
+func (j *imageJobs) handleDelete(ctx context.Context, job *models.Job) (string, error) {
+	var payload models.ImageJobPayload
+	if err := job.DecodePayload(&payload); err != nil {
+		return "", fmt.Errorf("invalid payload: %v", err)
+	}
+
+	// 释放文件并删除数据库记录
+	if err := j.releaseFiles(ctx, &payload, true); err != nil {
+		return "", fmt.Errorf("failed to delete image %d: %v", payload.ImageID, err)
+	}
+
+	// 已删除的图片不再需要过期
+	j.redisClient.ZRem(ctx, config.ImageExpireSchedule, strconv.Itoa(payload.ImageID))
+
+	j.publish(ctx, models.EventImageDeleted, job, &payload)
+	return "Image deleted successfully", nil
+}
+
+// handleExpire 处理过期任务
+func (j *imageJobs) handleExpire(ctx context.Context, job *models.Job) (string, error) {
+	var payload models.ImageJobPayload
+	if err := job.DecodePayload(&payload); err != nil {
+		return "", fmt.Errorf("invalid payload: %v", err)
+	}
+
+	// 释放文件并更新数据库状态为过期
+	if err := j.releaseFiles(ctx, &payload, false); err != nil {
+		return "", fmt.Errorf("failed to expire image %d: %v", payload.ImageID, err)
+	}
+
+	j.publish(ctx, models.EventImageExpired, job, &payload)
+	return "Image expired successfully", nil
+}
+
+// releaseFiles 释放图片占用的文件（共享内容在最后一个引用释放时删除）并清理变换缓存
+func (j *imageJobs) releaseFiles(ctx context.Context, payload *models.ImageJobPayload, remove bool) error {
+	if err := releaseImage(ctx, j.storage, payload.ImageID, remove, nil); err != nil {
+		return err
+	}
+	return j.imageService.EvictTransformCache(payload.ImageCode)
+}
+
+// publish 发布图片变更事件
+func (j *imageJobs) publish(ctx context.Context, eventType string, job *models.Job, payload *models.ImageJobPayload) {
+	events.Publish(ctx, j.redisClient, &models.Event{
+		Type:      eventType,
+		UserID:    job.OwnerID,
+		TaskID:    job.ID,
+		ImageID:   payload.ImageID,
+		ImageCode: payload.ImageCode,
+	})
+}
+
+// enqueueExpiredImages 为到期的图片生成过期任务。
+// 通过ZREM认领，多个进程同时轮询时每张图片只会被一个进程处理
+func (j *imageJobs) enqueueExpiredImages(ctx context.Context) {
+	due, err := j.redisClient.ZRangeByScore(ctx, config.ImageExpireSchedule, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Count: 100,
+	}).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to get due image expiries: %v", err)
+		}
+		return
+	}
+
+	for _, member := range due {
+		claimed, err := j.redisClient.ZRem(ctx, config.ImageExpireSchedule, member).Result()
+		if err != nil || claimed == 0 {
+			continue
+		}
+
+		imageID, err := strconv.Atoi(member)
+		if err != nil {
+			continue
+		}
+		image, err := j.imageService.GetImageByID(imageID, Requester{IsAdmin: true})
+		if errors.Is(err, ErrImageNotFound) {
+			continue // 已删除
+		}
+		if err != nil {
+			log.Printf("Failed to get expiring image %d: %v", imageID, err)
+			j.retryExpiry(ctx, member)
+			continue
+		}
+		if image.Status != "active" {
+			continue // 已过期
+		}
+
+		if _, err := j.imageService.ScheduleExpireTask(image); err != nil {
+			log.Printf("Failed to schedule expire task for image %s: %v", image.ImageCode, err)
+			j.retryExpiry(ctx, member)
+		}
+	}
+}
+
+// retryExpiry 认领后处理失败时重新登记，稍后重试
+func (j *imageJobs) retryExpiry(ctx context.Context, member string) {
+	err := j.redisClient.ZAdd(ctx, config.ImageExpireSchedule, redis.Z{
+		Score:  float64(time.Now().Add(expiryRetryDelay).UnixMilli()),
+		Member: member,
+	}).Err()
+	if err != nil {
+		log.Printf("Failed to reschedule expiry for image %s: %v", member, err)
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"go-admin/config"
+	"go-admin/database"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestImageJobs 创建与 s 共享依赖的图片任务
+func newTestImageJobs(s *testImageService) *imageJobs {
+	return &imageJobs{
+		redisClient:  s.redisClient,
+		imageService: s.ImageServiceImpl,
+		storage:      s.storage,
+	}
+}
+
+// scheduleDue 将成员登记为已到期
+func scheduleDue(t *testing.T, client *redis.Client, member string) {
+	t.Helper()
+	err := client.ZAdd(context.Background(), config.ImageExpireSchedule, redis.Z{
+		Score:  float64(time.Now().Add(-time.Second).UnixMilli()),
+		Member: member,
+	}).Err()
+	if err != nil {
+		t.Fatalf("schedule %s: %v", member, err)
+	}
+}
+
+func TestUploadSchedulesExpiry(t *testing.T) {
+	s := newTestImageService(t)
+	image := s.upload(t, newTestUpload(t, 4), 1)
+
+	// 过期时间表的分数就是精确的过期时间
+	score, err := s.redisClient.ZScore(context.Background(), config.ImageExpireSchedule, strconv.Itoa(image.ID)).Result()
+	if err != nil {
+		t.Fatalf("schedule entry for image %d: %v", image.ID, err)
+	}
+	if int64(score) != image.ExpireTime.UnixMilli() {
+		t.Fatalf("schedule score = %v, want %d", score, image.ExpireTime.UnixMilli())
+	}
+
+	// 尚未到期的图片不会生成过期任务
+	newTestImageJobs(s).enqueueExpiredImages(context.Background())
+	if n := s.redisClient.LLen(context.Background(), config.ImageExpireQueue).Val(); n != 0 {
+		t.Fatalf("expire queue has %d jobs, want 0", n)
+	}
+}
+
+func TestEnqueueExpiredImages(t *testing.T) {
+	s := newTestImageService(t)
+	j := newTestImageJobs(s)
+	ctx := context.Background()
+	image := s.upload(t, newTestUpload(t, 5), 1)
+
+	scheduleDue(t, s.redisClient, strconv.Itoa(image.ID))
+	scheduleDue(t, s.redisClient, strconv.Itoa(image.ID+1)) // 已删除的图片
+	j.enqueueExpiredImages(ctx)
+
+	if n := s.redisClient.ZCard(ctx, config.ImageExpireSchedule).Val(); n != 0 {
+		t.Fatalf("schedule has %d entries, want 0", n)
+	}
+	if n := s.redisClient.LLen(ctx, config.ImageExpireQueue).Val(); n != 1 {
+		t.Fatalf("expire queue has %d jobs, want 1", n)
+	}
+}
+
+func TestEnqueueExpiredImagesKeepsEntryOnLookupError(t *testing.T) {
+	s := newTestImageService(t)
+	j := newTestImageJobs(s)
+	ctx := context.Background()
+	image := s.upload(t, newTestUpload(t, 6), 1)
+	member := strconv.Itoa(image.ID)
+
+	scheduleDue(t, s.redisClient, member)
+	database.Close()
+	j.enqueueExpiredImages(ctx)
+
+	// 查询失败时重新登记并推迟，不能丢失过期时间
+	score, err := s.redisClient.ZScore(ctx, config.ImageExpireSchedule, member).Result()
+	if err != nil {
+		t.Fatalf("schedule entry for image %s: %v", member, err)
+	}
+	if score <= float64(time.Now().UnixMilli()) {
+		t.Fatalf("retry score %v is not in the future", score)
+	}
+	if n := s.redisClient.LLen(ctx, config.ImageExpireQueue).Val(); n != 0 {
+		t.Fatalf("expire queue has %d jobs, want 0", n)
+	}
+}
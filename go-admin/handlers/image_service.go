@@ -1,134 +1,304 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"image"
 	"io"
 	"log"
 	"mime/multipart"
-	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"go-admin/config"
 	"go-admin/database"
+	"go-admin/events"
+	"go-admin/imaging"
+	"go-admin/jobs"
 	"go-admin/models"
+	"go-admin/storage"
+	"go-admin/utils"
 
-	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
+// Requester 调用者身份，用于按上传者限定访问范围
+type Requester struct {
+	UserID  int
+	IsAdmin bool
+}
+
+// scope 非管理员只能访问自己上传的图片
+func (r Requester) scope(db *gorm.DB) *gorm.DB {
+	if r.IsAdmin {
+		return db
+	}
+	return db.Where("owner_id = ?", r.UserID)
+}
+
 // ImageService 图片服务接口
 type ImageService interface {
-	UploadImage(file *multipart.FileHeader, expireValue int, expireUnit string) (*models.Image, error)
-	GetImageByID(id int) (*models.Image, error)
+	UploadImage(file *multipart.FileHeader, expireValue int, expireUnit string, ownerID int) (*models.Image, error)
+	GetImageByID(id int, requester Requester) (*models.Image, error)
 	GetImageByCode(imageCode string) (*models.Image, error)
-	GetAllImages(page, pageSize int) (*models.ImageListResponse, error)
+	GetAllImages(page, pageSize int, requester Requester) (*models.ImageListResponse, error)
 	GetRandomImage() (*models.Image, error)
-	DeleteImage(id int) error
-	DeleteExpiredImages() error
-	ScheduleDeleteTask(imageID int, imageCode, filePath string) error
-	ScheduleExpireTask(imageID int, imageCode, filePath string) error
+	OpenImageFile(image *models.Image, size string) (storage.Object, error)
+	OpenTransformed(image *models.Image, opts imaging.TransformOptions) (storage.Object, error)
+	EvictTransformCache(imageCode string) error
+	DeleteImage(id int, requester Requester) (*models.Job, error)
+	DeleteExpiredImages(fence *database.Fence) error
+	ScheduleDeleteTask(image *models.Image) (*models.Job, error)
+	ScheduleExpireTask(image *models.Image) (*models.Job, error)
+	ScheduleExpiry(image *models.Image) error
 }
 
+// maxImageCodeAttempts 图片码冲突时最多尝试次数
+const maxImageCodeAttempts = 5
+
+// ErrUnknownVariant 请求了未配置的尺寸变体
+var ErrUnknownVariant = errors.New("unknown image size")
+
+// ErrImageNotFound 图片不存在或调用者无权访问
+var ErrImageNotFound = errors.New("image not found")
+
+// ErrImageForbidden 图片属于其他用户，调用者无权修改
+var ErrImageForbidden = errors.New("image belongs to another user")
+
 // ImageServiceImpl 图片服务实现
 type ImageServiceImpl struct {
-	uploadDir   string
-	redisClient *redis.Client
+	storage         storage.Backend
+	redisClient     *redis.Client
+	settings        *config.Store // 上传限制、变换尺寸和图片码规则可以热加载
+	variants        []config.VariantConfig
+	cleanupInterval time.Duration
+	cleanupStop     chan struct{}
+	cleanupWG       sync.WaitGroup
+	jobRegistry     *jobs.Registry
 }
 
 // NewImageService 创建图片服务
-func NewImageService(redisClient *redis.Client) *ImageServiceImpl {
-	// 创建上传目录
-	uploadDir := "./uploads/images"
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		panic(fmt.Sprintf("Failed to create upload directory: %v", err))
-	}
-
+func NewImageService(redisClient *redis.Client, backend storage.Backend, settings *config.Store, jobRegistry *jobs.Registry) *ImageServiceImpl {
+	imageConfig := settings.Get().Image
 	return &ImageServiceImpl{
-		uploadDir:   uploadDir,
-		redisClient: redisClient,
+		storage:         backend,
+		redisClient:     redisClient,
+		settings:        settings,
+		variants:        imageConfig.Variants,
+		cleanupInterval: imageConfig.CleanupInterval,
+		jobRegistry:     jobRegistry,
 	}
 }
 
 // UploadImage 上传图片
-func (s *ImageServiceImpl) UploadImage(file *multipart.FileHeader, expireValue int, expireUnit string) (*models.Image, error) {
-	// 验证文件类型
-	if !s.isValidImageType(file.Filename) {
-		return nil, errors.New("invalid image type, only support jpg, jpeg, png, gif")
-	}
+func (s *ImageServiceImpl) UploadImage(file *multipart.FileHeader, expireValue int, expireUnit string, ownerID int) (*models.Image, error) {
+	// 整个上传过程使用同一份配置
+	imageConfig := &s.settings.Get().Image
 
-	// 验证文件大小 (最大 10MB)
-	if file.Size > 10*1024*1024 {
-		return nil, errors.New("file size too large, maximum 10MB")
+	// 验证文件大小
+	if file.Size > imageConfig.MaxFileSize {
+		return nil, fmt.Errorf("file size too large, maximum %d bytes", imageConfig.MaxFileSize)
 	}
 
-	// 生成唯一图片码
-	imageCode := s.generateImageCode()
-
-	// 生成文件名
-	ext := filepath.Ext(file.Filename)
-	fileName := imageCode + ext
-	filePath := filepath.Join(s.uploadDir, fileName)
+	// 计算过期时间
+	var expireTime time.Time
+	switch expireUnit {
+	case "minutes":
+		expireTime = time.Now().Add(time.Duration(expireValue) * time.Minute)
+	case "hours":
+		expireTime = time.Now().Add(time.Duration(expireValue) * time.Hour)
+	case "days":
+		expireTime = time.Now().AddDate(0, 0, expireValue)
+	default:
+		return nil, errors.New("invalid time unit")
+	}
 
-	// 保存文件
 	src, err := file.Open()
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %v", err)
 	}
 	defer src.Close()
 
-	dst, err := os.Create(filePath)
+	// 根据文件内容校验图片类型和尺寸
+	info, decoded, err := imaging.Inspect(src, file.Filename, imaging.Limits{
+		Formats:   imageConfig.AllowedTypes,
+		MaxWidth:  imageConfig.MaxWidth,
+		MaxHeight: imageConfig.MaxHeight,
+		MaxPixels: imageConfig.MaxPixels,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create file: %v", err)
+		return nil, err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
 	}
-	defer dst.Close()
 
-	if _, err = io.Copy(dst, src); err != nil {
-		return nil, fmt.Errorf("failed to save file: %v", err)
+	// 计算内容哈希
+	contentHash, err := hashContent(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
 	}
 
-	// 计算过期时间
-	var expireTime time.Time
-	switch expireUnit {
-	case "minutes":
-		expireTime = time.Now().Add(time.Duration(expireValue) * time.Minute)
-	case "hours":
-		expireTime = time.Now().Add(time.Duration(expireValue) * time.Hour)
-	case "days":
-		expireTime = time.Now().AddDate(0, 0, expireValue)
-	default:
-		return nil, errors.New("invalid time unit")
+	// 登记内容引用，相同内容只存储一份
+	blob, claimed, err := acquireBlob(contentHash, info, file.Size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save image record: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := s.storeBlob(ctx, blob, claimed, src, decoded, info); err != nil {
+		s.releaseBlob(ctx, contentHash)
+		return nil, fmt.Errorf("failed to save file: %v", err)
 	}
 
+	ext := filepath.Ext(file.Filename)
+
 	// 创建图片记录
 	image := &models.Image{
-		ImageCode:  imageCode,
-		FileName:   file.Filename,
-		FilePath:   filePath,
-		FileSize:   file.Size,
-		FileType:   strings.ToLower(ext[1:]), // 去掉点号
-		ExpireTime: expireTime,
-		Status:     "active",
-	}
-
-	if err := database.DB.Create(image).Error; err != nil {
-		// 删除已保存的文件
-		os.Remove(filePath)
+		OwnerID:     ownerID,
+		FileName:    file.Filename,
+		FilePath:    blob.FilePath,
+		FileSize:    file.Size,
+		FileType:    strings.ToLower(ext[1:]), // 去掉点号
+		MimeType:    info.MimeType,
+		ContentHash: contentHash,
+		Width:       info.Width,
+		Height:      info.Height,
+		Variants:    blob.Variants,
+		ExpireTime:  expireTime,
+		Status:      "active",
+	}
+
+	if err := s.createWithUniqueCode(image, imageConfig); err != nil {
+		// 释放内容引用
+		s.releaseBlob(ctx, contentHash)
 		return nil, fmt.Errorf("failed to save image record: %v", err)
 	}
 
+	// 登记过期时间，到期后由任务处理器生成过期任务；失败时由定期清理兜底
+	if err := s.ScheduleExpiry(image); err != nil {
+		log.Printf("Failed to schedule expiry for image %s: %v", image.ImageCode, err)
+	}
+
+	events.Publish(ctx, s.redisClient, &models.Event{
+		Type:      models.EventImageUploaded,
+		UserID:    image.OwnerID,
+		ImageID:   image.ID,
+		ImageCode: image.ImageCode,
+	})
+
 	return image, nil
 }
 
+// storeBlob 等待内容就绪。认领了内容的调用方在事务外上传原图和变体，完成后标记为就绪；
+// 其他调用方轮询等待，上传者失败或超时未完成时接手上传
+func (s *ImageServiceImpl) storeBlob(ctx context.Context, blob *models.ImageBlob, claimed bool, src io.Reader, decoded image.Image, info *imaging.Info) error {
+	for !claimed {
+		var current models.ImageBlob
+		if err := database.DB.First(&current, blob.ID).Error; err != nil {
+			return err
+		}
+		if current.Status == blobReady {
+			*blob = current
+			return nil
+		}
+
+		var err error
+		if claimed, err = claimBlob(&current); err != nil {
+			return err
+		}
+		if claimed {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(blobPollInterval):
+		}
+	}
+
+	if err := s.storage.Put(ctx, blob.FilePath, src, blob.FileSize, info.MimeType); err != nil {
+		// 交给等待中的上传接手
+		if markErr := markBlob(blob, map[string]interface{}{"status": blobFailed}); markErr != nil {
+			log.Printf("Failed to mark blob %s as failed: %v", blob.ContentHash, markErr)
+		}
+		return err
+	}
+
+	variants := strings.Join(s.generateVariants(ctx, blob.FilePath, decoded, info.Format), ",")
+	if err := markBlob(blob, map[string]interface{}{"status": blobReady, "variants": variants}); err != nil {
+		return err
+	}
+	blob.Status = blobReady
+	blob.Variants = variants
+	return nil
+}
+
+// releaseBlob 上传失败时撤销已登记的内容引用
+func (s *ImageServiceImpl) releaseBlob(ctx context.Context, contentHash string) {
+	var orphaned []string
+	err := database.DB.Transaction(func(tx *gorm.DB) (err error) {
+		orphaned, _, err = releaseBlob(tx, contentHash)
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to release blob %s: %v", contentHash, err)
+		return
+	}
+	deleteStoredFiles(ctx, s.storage, orphaned)
+}
+
+// hashContent 计算内容的SHA-256，完成后将读取位置重置到开头
+func hashContent(r io.ReadSeeker) (string, error) {
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, r); err != nil {
+		return "", err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// generateVariants 生成配置的尺寸变体，原图不大于变体尺寸时跳过（直接使用原图）
+func (s *ImageServiceImpl) generateVariants(ctx context.Context, filePath string, decoded image.Image, format string) []string {
+	bounds := decoded.Bounds()
+	var generated []string
+	for _, variant := range s.variants {
+		if bounds.Dx() <= variant.Size && bounds.Dy() <= variant.Size {
+			continue
+		}
+
+		data, err := imaging.EncodeBytes(imaging.Fit(decoded, variant.Size), format, imaging.DefaultJPEGQuality)
+		if err != nil {
+			log.Printf("Failed to encode %s variant for %s: %v", variant.Name, filePath, err)
+			continue
+		}
+
+		key := models.VariantKey(variant.Name, filePath)
+		if err := s.storage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), imaging.MimeType(format)); err != nil {
+			log.Printf("Failed to save %s variant for %s: %v", variant.Name, filePath, err)
+			continue
+		}
+		generated = append(generated, variant.Name)
+	}
+	return generated
+}
+
 // GetImageByID 根据ID获取图片
-func (s *ImageServiceImpl) GetImageByID(id int) (*models.Image, error) {
+func (s *ImageServiceImpl) GetImageByID(id int, requester Requester) (*models.Image, error) {
 	var image models.Image
-	if err := database.DB.First(&image, id).Error; err != nil {
-		return nil, errors.New("image not found")
+	if err := database.DB.Scopes(requester.scope).First(&image, id).Error; err != nil {
+		return nil, imageQueryError(err)
 	}
 	return &image, nil
 }
@@ -137,24 +307,32 @@ func (s *ImageServiceImpl) GetImageByID(id int) (*models.Image, error) {
 func (s *ImageServiceImpl) GetImageByCode(imageCode string) (*models.Image, error) {
 	var image models.Image
 	if err := database.DB.Where("image_code = ?", imageCode).First(&image).Error; err != nil {
-		return nil, errors.New("image not found")
+		return nil, imageQueryError(err)
 	}
 	return &image, nil
 }
 
+// imageQueryError 区分图片不存在和查询失败
+func imageQueryError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrImageNotFound
+	}
+	return fmt.Errorf("failed to get image: %v", err)
+}
+
 // GetAllImages 获取所有图片（分页）
-func (s *ImageServiceImpl) GetAllImages(page, pageSize int) (*models.ImageListResponse, error) {
+func (s *ImageServiceImpl) GetAllImages(page, pageSize int, requester Requester) (*models.ImageListResponse, error) {
 	var images []models.Image
 	var total int64
 
 	// 获取总数
-	if err := database.DB.Model(&models.Image{}).Count(&total).Error; err != nil {
+	if err := database.DB.Model(&models.Image{}).Scopes(requester.scope).Count(&total).Error; err != nil {
 		return nil, err
 	}
 
 	// 分页查询
 	offset := (page - 1) * pageSize
-	if err := database.DB.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&images).Error; err != nil {
+	if err := database.DB.Scopes(requester.scope).Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&images).Error; err != nil {
 		return nil, err
 	}
 
@@ -176,7 +354,7 @@ func (s *ImageServiceImpl) GetRandomImage() (*models.Image, error) {
 
 	// 查询有效的图片（未过期且状态为active）
 	err := database.DB.Where("status = ? AND expire_time > ?", "active", time.Now()).
-		Order("RAND()").
+		Order(database.RandomOrder()).
 		First(&image).Error
 
 	if err != nil {
@@ -186,19 +364,132 @@ func (s *ImageServiceImpl) GetRandomImage() (*models.Image, error) {
 	return &image, nil
 }
 
-// DeleteImage 删除图片
-func (s *ImageServiceImpl) DeleteImage(id int) error {
+// OpenImageFile 打开图片文件用于读取，size为空或original时返回原图
+func (s *ImageServiceImpl) OpenImageFile(image *models.Image, size string) (storage.Object, error) {
+	ctx := context.Background()
+	if size == "" || size == "original" {
+		return s.storage.Open(ctx, image.FilePath)
+	}
+
+	if !s.isVariantConfigured(size) {
+		return nil, ErrUnknownVariant
+	}
+
+	// 原图小于变体尺寸时没有生成变体，直接返回原图
+	if !image.HasVariant(size) {
+		return s.storage.Open(ctx, image.FilePath)
+	}
+	return s.storage.Open(ctx, models.VariantKey(size, image.FilePath))
+}
+
+// OpenTransformed 打开按参数变换后的图片，结果缓存在存储后端中
+func (s *ImageServiceImpl) OpenTransformed(image *models.Image, opts imaging.TransformOptions) (storage.Object, error) {
+	sourceFormat := imaging.FormatOf(image.MimeType, image.FileType)
+	opts, err := opts.Normalize(sourceFormat, s.settings.Get().Image.TransformSizes)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	cacheKey := "transforms/" + image.ImageCode + "/" + opts.CacheKey()
+
+	// 命中缓存
+	object, err := s.storage.Open(ctx, cacheKey)
+	if err == nil {
+		return object, nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+
+	// 读取原图并变换
+	src, err := s.storage.Get(ctx, image.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	decoded, _, err := imaging.Decode(src)
+	src.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := imaging.EncodeBytes(imaging.Transform(decoded, opts), opts.Format, opts.Quality)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先登记缓存索引再写入，保证清理时不会遗漏
+	indexKey := config.ImageTransformCachePrefix + image.ImageCode
+	pipe := s.redisClient.TxPipeline()
+	pipe.SAdd(ctx, indexKey, cacheKey)
+	pipe.ExpireAt(ctx, indexKey, image.ExpireTime.Add(24*time.Hour))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.storage.Put(ctx, cacheKey, bytes.NewReader(data), int64(len(data)), imaging.MimeType(opts.Format)); err != nil {
+		return nil, err
+	}
+
+	return s.storage.Open(ctx, cacheKey)
+}
+
+// EvictTransformCache 清理图片的全部变换缓存
+func (s *ImageServiceImpl) EvictTransformCache(imageCode string) error {
+	ctx := context.Background()
+	indexKey := config.ImageTransformCachePrefix + imageCode
+
+	keys, err := s.redisClient.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.storage.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return s.redisClient.Del(ctx, indexKey).Err()
+}
+
+// isVariantConfigured 判断变体名称是否已配置
+func (s *ImageServiceImpl) isVariantConfigured(name string) bool {
+	for _, variant := range s.variants {
+		if variant.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteImage 删除图片，删除异步执行，返回删除任务。
+// 图片已有未结束的删除任务时直接返回该任务，不重复入队
+func (s *ImageServiceImpl) DeleteImage(id int, requester Requester) (*models.Job, error) {
 	var image models.Image
 	if err := database.DB.First(&image, id).Error; err != nil {
-		return errors.New("image not found")
+		return nil, imageQueryError(err)
+	}
+	if !requester.IsAdmin && image.OwnerID != requester.UserID {
+		return nil, ErrImageForbidden
+	}
+
+	var record models.TaskRecord
+	err := database.DB.
+		Where("image_id = ? AND type = ? AND status IN ?", image.ID, JobImageDelete,
+			[]string{config.TaskStatusPending, config.TaskStatusProcessing}).
+		Order("created_at DESC").
+		First(&record).Error
+	if err == nil {
+		return record.ToJob(), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
 	}
 
 	// 使用Redis异步删除
-	return s.ScheduleDeleteTask(image.ID, image.ImageCode, image.FilePath)
+	return s.ScheduleDeleteTask(&image)
 }
 
-// DeleteExpiredImages 删除过期图片
-func (s *ImageServiceImpl) DeleteExpiredImages() error {
+// DeleteExpiredImages 删除过期图片，fence为清理任务领导者的防护令牌
+func (s *ImageServiceImpl) DeleteExpiredImages(fence *database.Fence) error {
 	var expiredImages []models.Image
 	now := time.Now()
 
@@ -209,75 +500,156 @@ func (s *ImageServiceImpl) DeleteExpiredImages() error {
 
 	// 删除过期图片
 	for _, image := range expiredImages {
-		// 删除文件
-		if err := os.Remove(image.FilePath); err != nil && !os.IsNotExist(err) {
+		// 释放文件并更新状态为过期
+		if err := releaseImage(context.Background(), s.storage, image.ID, false, fence); err != nil {
+			if errors.Is(err, database.ErrStaleFence) {
+				return err // 已失去领导权，由新的领导者继续
+			}
 			continue // 继续删除其他图片
 		}
-
-		// 更新状态为过期
-		database.DB.Model(&image).Update("status", "expired")
+		if err := s.EvictTransformCache(image.ImageCode); err != nil {
+			log.Printf("Failed to evict transform cache for image %s: %v", image.ImageCode, err)
+		}
+		events.Publish(context.Background(), s.redisClient, &models.Event{
+			Type:      models.EventImageExpired,
+			UserID:    image.OwnerID,
+			ImageID:   image.ID,
+			ImageCode: image.ImageCode,
+		})
 	}
 
 	return nil
 }
 
-// StartCleanupScheduler 启动清理调度器
-func (s *ImageServiceImpl) StartCleanupScheduler() {
-	// 每小时检查一次过期图片
-	ticker := time.NewTicker(1 * time.Hour)
+// StartCleanupScheduler 启动清理调度器。
+// 图片到期由过期时间表精确触发，这里按 ImageConfig.CleanupInterval（默认每小时）全量检查一次作为兜底。
+// 多实例部署时只有清理任务的领导者执行
+func (s *ImageServiceImpl) StartCleanupScheduler(leader *utils.LeaderElector) {
+	s.cleanupStop = make(chan struct{})
+
+	// 登记升级前上传的图片
+	if leader.IsLeader() {
+		s.cleanupWG.Add(1)
+		go func() {
+			defer s.cleanupWG.Done()
+			if err := s.syncExpirySchedule(); err != nil {
+				log.Printf("Failed to sync image expiry schedule: %v", err)
+			}
+		}()
+	}
+
+	// 定期检查过期图片
+	s.cleanupWG.Add(1)
 	go func() {
+		defer s.cleanupWG.Done()
+		ticker := time.NewTicker(s.cleanupInterval)
+		defer ticker.Stop()
+
 		for {
 			select {
+			case <-s.cleanupStop:
+				return
 			case <-ticker.C:
-				if err := s.DeleteExpiredImages(); err != nil {
+				token, ok := leader.Token()
+				if !ok {
+					continue // 由其他实例执行
+				}
+				fence := &database.Fence{Name: leader.Name(), Token: token}
+				if err := s.DeleteExpiredImages(fence); err != nil {
 					log.Printf("Failed to cleanup expired images: %v", err)
 				} else {
 					log.Println("Expired images cleanup completed")
 				}
+				if err := s.syncExpirySchedule(); err != nil {
+					log.Printf("Failed to sync image expiry schedule: %v", err)
+				}
 			}
 		}
 	}()
 	log.Println("Image cleanup scheduler started")
 }
 
-// isValidImageType 验证图片类型
-func (s *ImageServiceImpl) isValidImageType(filename string) bool {
-	ext := strings.ToLower(filepath.Ext(filename))
-	validTypes := map[string]bool{
-		".jpg":  true,
-		".jpeg": true,
-		".png":  true,
-		".gif":  true,
+// StopCleanupScheduler 停止清理调度器，等待正在进行的清理完成
+func (s *ImageServiceImpl) StopCleanupScheduler() {
+	if s.cleanupStop == nil {
+		return
 	}
-	return validTypes[ext]
+	close(s.cleanupStop)
+	s.cleanupWG.Wait()
+	log.Println("Image cleanup scheduler stopped")
 }
 
-// generateImageCode 生成唯一图片码
-func (s *ImageServiceImpl) generateImageCode() string {
-	// 使用UUID生成唯一码，取前8位
-	return strings.ReplaceAll(uuid.New().String()[:8], "-", "")
+// syncExpirySchedule 将所有有效图片登记到过期时间表，已登记的只更新时间
+func (s *ImageServiceImpl) syncExpirySchedule() error {
+	var images []models.Image
+	return database.DB.Select("id", "expire_time").Where("status = ?", "active").
+		FindInBatches(&images, 500, func(tx *gorm.DB, batch int) error {
+			members := make([]redis.Z, len(images))
+			for i, image := range images {
+				members[i] = expiryMember(&image)
+			}
+			return s.redisClient.ZAdd(context.Background(), config.ImageExpireSchedule, members...).Err()
+		}).Error
 }
 
-// ScheduleDeleteTask 调度删除任务
-func (s *ImageServiceImpl) ScheduleDeleteTask(imageID int, imageCode, filePath string) error {
-	task := models.NewDeleteTask("delete", imageID, imageCode, filePath)
-	taskJSON, err := json.Marshal(task)
-	if err != nil {
-		return fmt.Errorf("failed to marshal delete task: %v", err)
+// createWithUniqueCode 生成图片码并创建图片记录，图片码与已有记录冲突时重新生成
+func (s *ImageServiceImpl) createWithUniqueCode(image *models.Image, imageConfig *config.ImageConfig) error {
+	for attempt := 1; ; attempt++ {
+		imageCode, err := generateImageCode(imageConfig)
+		if err != nil {
+			return fmt.Errorf("failed to generate image code: %v", err)
+		}
+		image.ImageCode = imageCode
+
+		err = database.DB.Create(image).Error
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return err
+		}
+		if attempt == maxImageCodeAttempts {
+			return fmt.Errorf("no unique image code after %d attempts: %v", attempt, err)
+		}
+		log.Printf("Image code %s already exists, retrying", imageCode)
 	}
+}
 
-	ctx := context.Background()
-	return s.redisClient.RPush(ctx, config.ImageDeleteQueue, taskJSON).Err()
+// generateImageCode 生成随机图片码，唯一性由数据库唯一索引保证，冲突时重新生成
+func generateImageCode(imageConfig *config.ImageConfig) (string, error) {
+	return utils.RandomString(imageConfig.CodeLength, imageConfig.CodeAlphabet)
+}
+
+// ScheduleDeleteTask 调度删除任务
+func (s *ImageServiceImpl) ScheduleDeleteTask(image *models.Image) (*models.Job, error) {
+	return s.enqueueImageJob(JobImageDelete, image)
+}
+
+// ScheduleExpiry 将图片登记到过期时间表
+func (s *ImageServiceImpl) ScheduleExpiry(image *models.Image) error {
+	return s.redisClient.ZAdd(context.Background(), config.ImageExpireSchedule, expiryMember(image)).Err()
+}
+
+// expiryMember 过期时间表成员，分数为过期时间（毫秒）
+func expiryMember(image *models.Image) redis.Z {
+	return redis.Z{
+		Score:  float64(image.ExpireTime.UnixMilli()),
+		Member: strconv.Itoa(image.ID),
+	}
 }
 
 // ScheduleExpireTask 调度过期任务
-func (s *ImageServiceImpl) ScheduleExpireTask(imageID int, imageCode, filePath string) error {
-	task := models.NewDeleteTask("expire", imageID, imageCode, filePath)
-	taskJSON, err := json.Marshal(task)
+func (s *ImageServiceImpl) ScheduleExpireTask(image *models.Image) (*models.Job, error) {
+	return s.enqueueImageJob(JobImageExpire, image)
+}
+
+// enqueueImageJob 创建图片任务并放入队列
+func (s *ImageServiceImpl) enqueueImageJob(jobType string, image *models.Image) (*models.Job, error) {
+	job, err := models.NewJob(jobType, models.NewImageJobPayload(image))
 	if err != nil {
-		return fmt.Errorf("failed to marshal expire task: %v", err)
+		return nil, err
 	}
-
-	return s.redisClient.RPush(context.Background(), config.ImageExpireQueue, taskJSON).Err()
+	job.OwnerID = image.OwnerID
+	job.ImageID = image.ID
+	if err := jobs.Enqueue(context.Background(), s.redisClient, s.jobRegistry, job); err != nil {
+		return nil, err
+	}
+	return job, nil
 }
- 
\ No newline at end of file
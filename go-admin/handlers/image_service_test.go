@@ -0,0 +1,755 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-admin/config"
+	"go-admin/database"
+	"go-admin/imaging"
+	"go-admin/jobs"
+	"go-admin/models"
+	"go-admin/storage"
+	"go-admin/testutil"
+	"go-admin/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// testImageService 测试使用的图片服务及其依赖
+type testImageService struct {
+	*ImageServiceImpl
+	redisClient *redis.Client
+	storage     storage.Backend
+	registry    *jobs.Registry
+}
+
+// newTestImageService 创建使用临时数据库、内存Redis和本地存储的图片服务，并注册图片任务
+func newTestImageService(t *testing.T) *testImageService {
+	t.Helper()
+	cfg := testutil.Config(t)
+	cfg.Image.Variants = []config.VariantConfig{{Name: "thumb", Size: 16}, {Name: "medium", Size: 32}}
+	testutil.NewDB(t, cfg)
+	_, client := testutil.NewRedis(t)
+
+	backend, err := storage.NewLocalBackend(cfg.Storage.LocalDir)
+	if err != nil {
+		t.Fatalf("NewLocalBackend: %v", err)
+	}
+	registry := jobs.NewRegistry()
+	service := NewImageService(client, backend, config.NewStore(cfg, nil), registry)
+	RegisterImageJobs(registry, client, service, backend)
+
+	return &testImageService{
+		ImageServiceImpl: service,
+		redisClient:      client,
+		storage:          backend,
+		registry:         registry,
+	}
+}
+
+// newTestUpload 生成一张大于全部测试变体尺寸的PNG，seed不同时内容不同
+func newTestUpload(t *testing.T, seed uint8) *multipart.FileHeader {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: seed, A: 255})
+		}
+	}
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "test.png")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	part.Write(encoded.Bytes())
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(32 << 20)
+	if err != nil {
+		t.Fatalf("ReadForm: %v", err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["file"][0]
+}
+
+// upload 以 ownerID 的身份上传图片
+func (s *testImageService) upload(t *testing.T, file *multipart.FileHeader, ownerID int) *models.Image {
+	t.Helper()
+	image, err := s.UploadImage(file, 1, "hours", ownerID)
+	if err != nil {
+		t.Fatalf("UploadImage: %v", err)
+	}
+	return image
+}
+
+// loadBlob 读取内容记录，不存在时返回nil
+func loadBlob(t *testing.T, contentHash string) *models.ImageBlob {
+	t.Helper()
+	var blobs []models.ImageBlob
+	if err := database.DB.Where("content_hash = ?", contentHash).Find(&blobs).Error; err != nil {
+		t.Fatalf("load blob: %v", err)
+	}
+	if len(blobs) == 0 {
+		return nil
+	}
+	return &blobs[0]
+}
+
+// assertStored 检查原图及其变体是否存在于存储中
+func (s *testImageService) assertStored(t *testing.T, image *models.Image, want bool) {
+	t.Helper()
+	for _, key := range append([]string{image.FilePath}, image.VariantPaths()...) {
+		_, err := s.storage.Stat(context.Background(), key)
+		if want && err != nil {
+			t.Fatalf("Stat(%s): %v", key, err)
+		}
+		if !want && !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("Stat(%s) = %v, want ErrNotFound", key, err)
+		}
+	}
+}
+
+func TestUploadImageSharesContent(t *testing.T) {
+	s := newTestImageService(t)
+	file := newTestUpload(t, 1)
+
+	first := s.upload(t, file, 1)
+	second := s.upload(t, file, 2)
+
+	if first.ImageCode == second.ImageCode {
+		t.Fatalf("both uploads got code %s", first.ImageCode)
+	}
+	if first.FilePath != second.FilePath || first.ContentHash != second.ContentHash {
+		t.Fatalf("uploads do not share content: %s vs %s", first.FilePath, second.FilePath)
+	}
+	if first.Variants == "" || first.Variants != second.Variants {
+		t.Fatalf("variants = %q and %q, want the same non-empty list", first.Variants, second.Variants)
+	}
+	if blob := loadBlob(t, first.ContentHash); blob == nil || blob.RefCount != 2 {
+		t.Fatalf("blob = %+v, want ref_count 2", blob)
+	}
+
+	// 释放一个引用后文件仍然保留
+	if err := releaseImage(context.Background(), s.storage, first.ID, true, nil); err != nil {
+		t.Fatalf("releaseImage(first): %v", err)
+	}
+	if blob := loadBlob(t, first.ContentHash); blob == nil || blob.RefCount != 1 {
+		t.Fatalf("blob = %+v, want ref_count 1", blob)
+	}
+	s.assertStored(t, second, true)
+
+	// 最后一个引用释放时删除内容记录和文件
+	if err := releaseImage(context.Background(), s.storage, second.ID, false, nil); err != nil {
+		t.Fatalf("releaseImage(second): %v", err)
+	}
+	if blob := loadBlob(t, first.ContentHash); blob != nil {
+		t.Fatalf("blob = %+v, want deleted", blob)
+	}
+	s.assertStored(t, second, false)
+
+	var expired models.Image
+	if err := database.DB.First(&expired, second.ID).Error; err != nil {
+		t.Fatalf("load image: %v", err)
+	}
+	if expired.Status != "expired" {
+		t.Fatalf("status = %s, want expired", expired.Status)
+	}
+
+	// 重复释放不会再次减少引用
+	if err := releaseImage(context.Background(), s.storage, second.ID, false, nil); err != nil {
+		t.Fatalf("releaseImage(second) again: %v", err)
+	}
+}
+
+func TestUploadImageConcurrentSameContent(t *testing.T) {
+	s := newTestImageService(t)
+	file := newTestUpload(t, 2)
+
+	const uploads = 8
+	images := make([]*models.Image, uploads)
+	errs := make([]error, uploads)
+	var wg sync.WaitGroup
+	for i := 0; i < uploads; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			images[i], errs[i] = s.UploadImage(file, 1, "hours", 1)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("upload %d: %v", i, err)
+		}
+	}
+	// 后完成的上传复用先完成的上传生成的变体
+	for i, image := range images {
+		if image.Variants == "" || image.Variants != images[0].Variants {
+			t.Fatalf("upload %d variants = %q, want %q", i, image.Variants, images[0].Variants)
+		}
+	}
+	if blob := loadBlob(t, images[0].ContentHash); blob == nil || blob.RefCount != uploads {
+		t.Fatalf("blob = %+v, want ref_count %d", blob, uploads)
+	}
+
+	var wgRelease sync.WaitGroup
+	for i, image := range images {
+		wgRelease.Add(1)
+		go func(i, id int) {
+			defer wgRelease.Done()
+			errs[i] = releaseImage(context.Background(), s.storage, id, true, nil)
+		}(i, image.ID)
+	}
+	wgRelease.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("release %d: %v", i, err)
+		}
+	}
+	if blob := loadBlob(t, images[0].ContentHash); blob != nil {
+		t.Fatalf("blob = %+v, want deleted", blob)
+	}
+	s.assertStored(t, images[0], false)
+}
+
+func TestUploadImageTakesOverUnfinishedContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates map[string]interface{}
+	}{
+		{"failed upload", map[string]interface{}{"status": blobFailed}},
+		// 上传者认领后退出，没有标记结果
+		{"abandoned upload", map[string]interface{}{"status": blobPending, "updated_at": time.Now().Add(-2 * blobClaimTimeout)}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestImageService(t)
+			file := newTestUpload(t, uint8(10+i))
+			first := s.upload(t, file, 1)
+
+			// 模拟上传没有完成：记录未就绪且文件不存在
+			if err := database.DB.Model(&models.ImageBlob{}).Where("content_hash = ?", first.ContentHash).
+				UpdateColumns(tt.updates).Error; err != nil {
+				t.Fatalf("update blob: %v", err)
+			}
+			deleteStoredFiles(context.Background(), s.storage, storedFiles(first.FilePath, first.VariantPaths()))
+
+			second := s.upload(t, file, 2)
+			if second.FilePath != first.FilePath || second.Variants != first.Variants {
+				t.Fatalf("second upload = %s %q, want %s %q", second.FilePath, second.Variants, first.FilePath, first.Variants)
+			}
+			s.assertStored(t, second, true)
+			if blob := loadBlob(t, first.ContentHash); blob.Status != blobReady || blob.RefCount != 2 {
+				t.Fatalf("blob = %+v, want ready with ref_count 2", blob)
+			}
+		})
+	}
+}
+
+func TestUploadImageWaitsForUploader(t *testing.T) {
+	s := newTestImageService(t)
+	file := newTestUpload(t, 3)
+	first := s.upload(t, file, 1)
+
+	// 另一个上传者刚刚认领，等待其完成而不是重复上传
+	if err := database.DB.Model(&models.ImageBlob{}).Where("content_hash = ?", first.ContentHash).
+		Update("status", blobPending).Error; err != nil {
+		t.Fatalf("update blob: %v", err)
+	}
+
+	done := make(chan *models.Image)
+	go func() {
+		image, err := s.UploadImage(file, 1, "hours", 2)
+		if err != nil {
+			t.Errorf("UploadImage: %v", err)
+		}
+		done <- image
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("upload finished while the content was still pending")
+	case <-time.After(5 * blobPollInterval):
+	}
+
+	if err := database.DB.Model(&models.ImageBlob{}).Where("content_hash = ?", first.ContentHash).
+		Update("status", blobReady).Error; err != nil {
+		t.Fatalf("update blob: %v", err)
+	}
+	select {
+	case second := <-done:
+		if second == nil || second.Variants != first.Variants {
+			t.Fatalf("second upload = %+v, want variants %q", second, first.Variants)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("upload did not finish after the content became ready")
+	}
+}
+
+func TestUploadImageFailedPutReleasesContent(t *testing.T) {
+	s := newTestImageService(t)
+	s.ImageServiceImpl.storage = failingPutBackend{s.storage}
+	file := newTestUpload(t, 4)
+
+	if _, err := s.UploadImage(file, 1, "hours", 1); err == nil {
+		t.Fatalf("UploadImage succeeded with a failing storage backend")
+	}
+	var count int64
+	if err := database.DB.Model(&models.ImageBlob{}).Count(&count).Error; err != nil {
+		t.Fatalf("count blobs: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("%d blob records left after a failed upload", count)
+	}
+}
+
+// failingPutBackend 写入总是失败的存储后端
+type failingPutBackend struct {
+	storage.Backend
+}
+
+func (failingPutBackend) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	return errors.New("storage unavailable")
+}
+
+func TestReleaseImageKeepsFilesOnRollback(t *testing.T) {
+	s := newTestImageService(t)
+	image := s.upload(t, newTestUpload(t, 5), 1)
+
+	// 删除图片记录失败，事务回滚
+	err := database.DB.Callback().Delete().Before("gorm:delete").Register("test:fail_image_delete", func(db *gorm.DB) {
+		if db.Statement.Table == "images" {
+			db.AddError(errors.New("delete failed"))
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	if err := releaseImage(context.Background(), s.storage, image.ID, true, nil); err == nil {
+		t.Fatalf("releaseImage succeeded although the delete failed")
+	}
+	database.DB.Callback().Delete().Remove("test:fail_image_delete")
+
+	// 引用计数和文件都保持不变
+	if blob := loadBlob(t, image.ContentHash); blob == nil || blob.RefCount != 1 {
+		t.Fatalf("blob = %+v, want ref_count 1", blob)
+	}
+	s.assertStored(t, image, true)
+
+	if err := releaseImage(context.Background(), s.storage, image.ID, true, nil); err != nil {
+		t.Fatalf("releaseImage: %v", err)
+	}
+	s.assertStored(t, image, false)
+}
+
+func TestReleasedContentUploadedAgainUsesNewKey(t *testing.T) {
+	s := newTestImageService(t)
+	file := newTestUpload(t, 6)
+	first := s.upload(t, file, 1)
+	if err := releaseImage(context.Background(), s.storage, first.ID, true, nil); err != nil {
+		t.Fatalf("releaseImage: %v", err)
+	}
+
+	// 旧文件在提交后删除，不会删除再次上传的文件
+	second := s.upload(t, file, 1)
+	if second.FilePath == first.FilePath {
+		t.Fatalf("content uploaded again reuses key %s", first.FilePath)
+	}
+	deleteStoredFiles(context.Background(), s.storage, storedFiles(first.FilePath, first.VariantPaths()))
+	s.assertStored(t, second, true)
+}
+
+func TestReleaseImageRejectsStaleFence(t *testing.T) {
+	s := newTestImageService(t)
+	image := s.upload(t, newTestUpload(t, 3), 1)
+
+	// 新领导者已经使用了更大的令牌
+	current := &database.Fence{Name: "image-cleanup", Token: 5}
+	if err := releaseImage(context.Background(), s.storage, 0, false, current); err != nil {
+		t.Fatalf("releaseImage with current fence: %v", err)
+	}
+
+	stale := &database.Fence{Name: "image-cleanup", Token: 4}
+	err := releaseImage(context.Background(), s.storage, image.ID, false, stale)
+	if !errors.Is(err, database.ErrStaleFence) {
+		t.Fatalf("releaseImage with stale fence = %v, want ErrStaleFence", err)
+	}
+
+	var unchanged models.Image
+	if err := database.DB.First(&unchanged, image.ID).Error; err != nil {
+		t.Fatalf("load image: %v", err)
+	}
+	if unchanged.Status != "active" {
+		t.Fatalf("status = %s, want active", unchanged.Status)
+	}
+	if blob := loadBlob(t, image.ContentHash); blob == nil || blob.RefCount != 1 {
+		t.Fatalf("blob = %+v, want ref_count 1", blob)
+	}
+	s.assertStored(t, image, true)
+}
+
+func TestCleanupFenceSurvivesRedisReset(t *testing.T) {
+	s := newTestImageService(t)
+	image := s.upload(t, newTestUpload(t, 7), 1)
+	ctx := context.Background()
+
+	// 上一任领导者已经以令牌5写入
+	if err := releaseImage(ctx, s.storage, 0, false, &database.Fence{Name: "cleanup", Token: 5}); err != nil {
+		t.Fatalf("releaseImage with token 5: %v", err)
+	}
+
+	// Redis数据丢失后重新选举，令牌计数器从0开始
+	if err := s.redisClient.FlushAll(ctx).Err(); err != nil {
+		t.Fatalf("FlushAll: %v", err)
+	}
+	elector := utils.NewLeaderElector(s.redisClient, "cleanup", time.Minute)
+	elector.SetTokenFloor(database.FenceToken)
+	elector.Start()
+	defer elector.Stop()
+
+	token, ok := elector.Token()
+	if !ok || token <= 5 {
+		t.Fatalf("Token() = %d, %v; want a token above 5", token, ok)
+	}
+	if err := releaseImage(ctx, s.storage, image.ID, false, &database.Fence{Name: elector.Name(), Token: token}); err != nil {
+		t.Fatalf("releaseImage with new leader token: %v", err)
+	}
+	s.assertStored(t, image, false)
+}
+
+func TestGetImageByID(t *testing.T) {
+	s := newTestImageService(t)
+	image := s.upload(t, newTestUpload(t, 4), 1)
+
+	if _, err := s.GetImageByID(image.ID, Requester{UserID: 1}); err != nil {
+		t.Fatalf("GetImageByID(owner): %v", err)
+	}
+	if _, err := s.GetImageByID(image.ID, Requester{UserID: 2, IsAdmin: true}); err != nil {
+		t.Fatalf("GetImageByID(admin): %v", err)
+	}
+	// 其他用户的图片与不存在的图片返回相同的错误
+	if _, err := s.GetImageByID(image.ID, Requester{UserID: 2}); !errors.Is(err, ErrImageNotFound) {
+		t.Fatalf("GetImageByID(other user) = %v, want ErrImageNotFound", err)
+	}
+	if _, err := s.GetImageByID(image.ID+1, Requester{IsAdmin: true}); !errors.Is(err, ErrImageNotFound) {
+		t.Fatalf("GetImageByID(missing) = %v, want ErrImageNotFound", err)
+	}
+
+	// 数据库错误不能当作图片不存在
+	database.Close()
+	if _, err := s.GetImageByID(image.ID, Requester{IsAdmin: true}); err == nil || errors.Is(err, ErrImageNotFound) {
+		t.Fatalf("GetImageByID with closed database = %v, want a non-not-found error", err)
+	}
+}
+
+func TestGetAllImagesScopedToOwner(t *testing.T) {
+	s := newTestImageService(t)
+	s.upload(t, newTestUpload(t, 1), 1)
+	s.upload(t, newTestUpload(t, 2), 1)
+	s.upload(t, newTestUpload(t, 3), 2)
+
+	tests := []struct {
+		name      string
+		requester Requester
+		want      int
+	}{
+		{"owner", Requester{UserID: 1}, 2},
+		{"other owner", Requester{UserID: 2}, 1},
+		{"no uploads", Requester{UserID: 3}, 0},
+		{"admin", Requester{UserID: 3, IsAdmin: true}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := s.GetAllImages(1, 10, tt.requester)
+			if err != nil {
+				t.Fatalf("GetAllImages: %v", err)
+			}
+			if result.Total != tt.want || len(result.Items) != tt.want {
+				t.Fatalf("GetAllImages = %d items (total %d), want %d", len(result.Items), result.Total, tt.want)
+			}
+			for _, item := range result.Items {
+				if !tt.requester.IsAdmin && item.OwnerID != tt.requester.UserID {
+					t.Fatalf("item %d belongs to user %d", item.ID, item.OwnerID)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteImageScopedToOwner(t *testing.T) {
+	s := newTestImageService(t)
+	image := s.upload(t, newTestUpload(t, 1), 1)
+
+	if _, err := s.DeleteImage(image.ID, Requester{UserID: 2}); !errors.Is(err, ErrImageForbidden) {
+		t.Fatalf("DeleteImage(other user) = %v, want ErrImageForbidden", err)
+	}
+	if _, err := s.DeleteImage(image.ID+1, Requester{IsAdmin: true}); !errors.Is(err, ErrImageNotFound) {
+		t.Fatalf("DeleteImage(missing) = %v, want ErrImageNotFound", err)
+	}
+	job, err := s.DeleteImage(image.ID, Requester{UserID: 1})
+	if err != nil {
+		t.Fatalf("DeleteImage(owner): %v", err)
+	}
+	if job.OwnerID != 1 || job.ImageID != image.ID {
+		t.Fatalf("job = %+v, want owner 1 and image %d", job, image.ID)
+	}
+}
+
+// readObject 读取存储对象的全部内容
+func readObject(t *testing.T, object storage.Object) []byte {
+	t.Helper()
+	defer object.Close()
+	data, err := io.ReadAll(object)
+	if err != nil {
+		t.Fatalf("read object: %v", err)
+	}
+	return data
+}
+
+func TestOpenImageFileVariants(t *testing.T) {
+	s := newTestImageService(t)
+	image := s.upload(t, newTestUpload(t, 1), 1)
+
+	// 64x48 的原图生成 16 和 32 两个变体
+	for size, want := range map[string]int{"thumb": 16, "medium": 32, "original": 64, "": 64} {
+		object, err := s.OpenImageFile(image, size)
+		if err != nil {
+			t.Fatalf("OpenImageFile(%q): %v", size, err)
+		}
+		decoded, _, err := imaging.Decode(bytes.NewReader(readObject(t, object)))
+		if err != nil {
+			t.Fatalf("decode %q: %v", size, err)
+		}
+		if width := decoded.Bounds().Dx(); width != want {
+			t.Fatalf("OpenImageFile(%q) width = %d, want %d", size, width, want)
+		}
+	}
+
+	if _, err := s.OpenImageFile(image, "huge"); !errors.Is(err, ErrUnknownVariant) {
+		t.Fatalf("OpenImageFile(huge) = %v, want ErrUnknownVariant", err)
+	}
+
+	// 原图小于变体尺寸时不生成变体，返回原图
+	small := *image
+	small.Variants = "thumb"
+	object, err := s.OpenImageFile(&small, "medium")
+	if err != nil {
+		t.Fatalf("OpenImageFile(medium) without variant: %v", err)
+	}
+	original, err := s.OpenImageFile(image, "original")
+	if err != nil {
+		t.Fatalf("OpenImageFile(original): %v", err)
+	}
+	if !bytes.Equal(readObject(t, object), readObject(t, original)) {
+		t.Fatalf("missing variant did not fall back to the original")
+	}
+}
+
+func TestOpenTransformedCachesResult(t *testing.T) {
+	s := newTestImageService(t)
+	image := s.upload(t, newTestUpload(t, 1), 1)
+	ctx := context.Background()
+	opts := imaging.TransformOptions{Width: 100, Height: 100, Fit: imaging.FitCover, Format: "jpeg", Quality: 72}
+
+	object, err := s.OpenTransformed(image, opts)
+	if err != nil {
+		t.Fatalf("OpenTransformed: %v", err)
+	}
+	decoded, format, err := imaging.Decode(bytes.NewReader(readObject(t, object)))
+	if err != nil {
+		t.Fatalf("decode transformed: %v", err)
+	}
+	if format != "jpeg" || decoded.Bounds().Dx() != 100 || decoded.Bounds().Dy() != 100 {
+		t.Fatalf("transformed = %s %v, want jpeg 100x100", format, decoded.Bounds())
+	}
+
+	// 结果按图片码和规范化后的参数缓存，并登记到索引集合
+	cacheKey := "transforms/" + image.ImageCode + "/w100_h100_cover_q70.jpeg"
+	if _, err := s.storage.Stat(ctx, cacheKey); err != nil {
+		t.Fatalf("cached object %s: %v", cacheKey, err)
+	}
+	indexKey := config.ImageTransformCachePrefix + image.ImageCode
+	if members := s.redisClient.SMembers(ctx, indexKey).Val(); len(members) != 1 || members[0] != cacheKey {
+		t.Fatalf("cache index = %v, want [%s]", members, cacheKey)
+	}
+	if ttl := s.redisClient.TTL(ctx, indexKey).Val(); ttl <= 0 {
+		t.Fatalf("cache index TTL = %v, want an expiry", ttl)
+	}
+
+	// 替换缓存内容，等价参数的请求直接返回缓存
+	marker := []byte("cached")
+	if err := s.storage.Put(ctx, cacheKey, bytes.NewReader(marker), int64(len(marker)), "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	equivalent := imaging.TransformOptions{Width: 100, Height: 100, Fit: imaging.FitCover, Format: "jpeg", Quality: 68}
+	object, err = s.OpenTransformed(image, equivalent)
+	if err != nil {
+		t.Fatalf("OpenTransformed(equivalent): %v", err)
+	}
+	if got := readObject(t, object); !bytes.Equal(got, marker) {
+		t.Fatalf("equivalent transform was not served from cache")
+	}
+
+	// 另一组参数生成新的缓存项
+	if _, err := s.OpenTransformed(image, imaging.TransformOptions{Width: 100}); err != nil {
+		t.Fatalf("OpenTransformed(contain): %v", err)
+	}
+	if n := s.redisClient.SCard(ctx, indexKey).Val(); n != 2 {
+		t.Fatalf("cache index has %d entries, want 2", n)
+	}
+
+	// 清理时删除索引中的全部缓存文件和索引本身
+	if err := s.EvictTransformCache(image.ImageCode); err != nil {
+		t.Fatalf("EvictTransformCache: %v", err)
+	}
+	if _, err := s.storage.Stat(ctx, cacheKey); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("cached object after eviction: %v", err)
+	}
+	if _, err := s.storage.Stat(ctx, "transforms/"+image.ImageCode+"/w100_h0_contain_q0.png"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("second cached object after eviction: %v", err)
+	}
+	if n := s.redisClient.Exists(ctx, indexKey).Val(); n != 0 {
+		t.Fatalf("cache index still exists after eviction")
+	}
+	// 原图不受影响
+	if _, err := s.storage.Stat(ctx, image.FilePath); err != nil {
+		t.Fatalf("original after eviction: %v", err)
+	}
+	if err := s.EvictTransformCache(image.ImageCode); err != nil {
+		t.Fatalf("EvictTransformCache without cache: %v", err)
+	}
+}
+
+func TestOpenTransformedRejectsInvalidOptions(t *testing.T) {
+	s := newTestImageService(t)
+	image := s.upload(t, newTestUpload(t, 1), 1)
+
+	tests := []imaging.TransformOptions{
+		{Width: 150},                 // 不在 IMAGE_TRANSFORM_SIZES 中
+		{Width: 100, Fit: "stretch"}, // 未知缩放模式
+		{Width: 100, Format: "webp"}, // 不支持的格式
+		{Width: 100, Format: "jpeg", Quality: 200},
+	}
+	for _, opts := range tests {
+		if _, err := s.OpenTransformed(image, opts); !errors.Is(err, imaging.ErrInvalidTransform) {
+			t.Errorf("OpenTransformed(%+v) = %v, want ErrInvalidTransform", opts, err)
+		}
+	}
+	if n := s.redisClient.Exists(context.Background(), config.ImageTransformCachePrefix+image.ImageCode).Val(); n != 0 {
+		t.Fatalf("invalid transforms were registered in the cache index")
+	}
+}
+
+// forceDuplicateCodes 前 n 次插入图片时改用已存在的图片码，返回插入次数的计数
+func forceDuplicateCodes(t *testing.T, takenCode string, n int) *int {
+	t.Helper()
+	attempts := 0
+	err := database.DB.Callback().Create().Before("gorm:create").Register("test:duplicate_code", func(db *gorm.DB) {
+		image, ok := db.Statement.Dest.(*models.Image)
+		if !ok {
+			return
+		}
+		attempts++
+		if attempts <= n {
+			image.ImageCode = takenCode
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	t.Cleanup(func() { database.DB.Callback().Create().Remove("test:duplicate_code") })
+	return &attempts
+}
+
+func TestCreateWithUniqueCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		duplicates int
+		wantErr    bool
+	}{
+		{"no collision", 0, false},
+		{"retries after collisions", 2, false},
+		{"last attempt succeeds", maxImageCodeAttempts - 1, false},
+		{"gives up", maxImageCodeAttempts, true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestImageService(t)
+			taken := s.upload(t, newTestUpload(t, uint8(40+i)), 1)
+			imageConfig := &s.settings.Get().Image
+
+			attempts := forceDuplicateCodes(t, taken.ImageCode, tt.duplicates)
+			image := &models.Image{
+				OwnerID:    1,
+				FileName:   "copy.png",
+				FilePath:   taken.FilePath,
+				FileType:   "png",
+				ExpireTime: time.Now().Add(time.Hour),
+				Status:     "active",
+			}
+			err := s.createWithUniqueCode(image, imageConfig)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("createWithUniqueCode succeeded after %d collisions", tt.duplicates)
+				}
+				if !strings.Contains(err.Error(), "attempts") {
+					t.Fatalf("createWithUniqueCode = %v, want the attempts error", err)
+				}
+			} else if err != nil {
+				t.Fatalf("createWithUniqueCode: %v", err)
+			}
+
+			want := tt.duplicates + 1
+			if tt.wantErr {
+				want = maxImageCodeAttempts
+			}
+			if *attempts != want {
+				t.Fatalf("%d insert attempts, want %d", *attempts, want)
+			}
+			if !tt.wantErr {
+				if image.ImageCode == taken.ImageCode || len(image.ImageCode) != imageConfig.CodeLength {
+					t.Fatalf("image code = %q", image.ImageCode)
+				}
+				if strings.Trim(image.ImageCode, imageConfig.CodeAlphabet) != "" {
+					t.Fatalf("image code %q uses characters outside %q", image.ImageCode, imageConfig.CodeAlphabet)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateWithUniqueCodeDoesNotRetryOtherErrors(t *testing.T) {
+	s := newTestImageService(t)
+	attempts := forceDuplicateCodes(t, "", 0)
+
+	// 图片码冲突以外的错误直接返回
+	database.Close()
+	err := s.createWithUniqueCode(&models.Image{OwnerID: 1}, &s.settings.Get().Image)
+	if err == nil || errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Fatalf("createWithUniqueCode = %v, want the database error", err)
+	}
+	if *attempts != 1 {
+		t.Fatalf("%d insert attempts, want 1", *attempts)
+	}
+}
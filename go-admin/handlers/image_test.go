@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go-admin/database"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestImageRouter 按 routes 包的路径挂载图片接口，受保护的接口使用 claims 作为调用者身份
+func newTestImageRouter(s *testImageService, claims *utils.Claims) *gin.Engine {
+	handler := NewImageHandler(s.ImageServiceImpl)
+	router := gin.New()
+	router.GET("/images/code/:code", handler.GetImageByCode)
+	router.GET("/images/file/:code", handler.ServeImage)
+
+	protected := router.Group("/images", func(c *gin.Context) {
+		c.Set("claims", claims)
+		c.Next()
+	})
+	protected.GET("/:id", handler.GetImage)
+	protected.DELETE("/:id", handler.DeleteImage)
+	return router
+}
+
+// serve 发送请求并返回响应，header 为额外的请求头
+func serve(router http.Handler, method, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// decodeData 解析统一响应中的 data 字段
+func decodeData(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+	return body.Data
+}
+
+func TestGetImageByCodeHidesPrivateFields(t *testing.T) {
+	s := newTestImageService(t)
+	image := s.upload(t, newTestUpload(t, 1), 7)
+	router := newTestImageRouter(s, &utils.Claims{UserID: 7})
+
+	// 公开接口不暴露上传者、存储路径和内容哈希
+	w := serve(router, http.MethodGet, "/images/code/"+image.ImageCode, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /images/code = %d, want 200", w.Code)
+	}
+	public := decodeData(t, w)
+	if public["image_code"] != image.ImageCode {
+		t.Fatalf("image_code = %v, want %s", public["image_code"], image.ImageCode)
+	}
+	for _, field := range []string{"owner_id", "file_path", "content_hash"} {
+		if _, ok := public[field]; ok {
+			t.Errorf("public response contains %s", field)
+		}
+	}
+
+	// 上传者通过认证接口仍可获取完整信息
+	w = serve(router, http.MethodGet, "/images/"+strconv.Itoa(image.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /images/:id = %d, want 200", w.Code)
+	}
+	if owner := decodeData(t, w)["owner_id"]; owner != float64(7) {
+		t.Fatalf("owner_id = %v, want 7", owner)
+	}
+
+	if w := serve(router, http.MethodGet, "/images/code/missing", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("GET /images/code/missing = %d, want 404", w.Code)
+	}
+}
+
+func TestServeImageCaching(t *testing.T) {
+	s := newTestImageService(t)
+	image := s.upload(t, newTestUpload(t, 2), 1)
+	router := newTestImageRouter(s, nil)
+	path := "/images/file/" + image.ImageCode
+
+	w := serve(router, http.MethodGet, path, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d, want 200", path, w.Code)
+	}
+	// 原图的强ETag即内容哈希
+	etag := w.Header().Get("ETag")
+	if etag != `"`+image.ContentHash+`"` {
+		t.Fatalf("ETag = %q, want %q", etag, `"`+image.ContentHash+`"`)
+	}
+	if w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("Content-Type = %q", w.Header().Get("Content-Type"))
+	}
+	size := w.Body.Len()
+
+	// 变体使用不同的ETag
+	variant := serve(router, http.MethodGet, path+"?size=thumb", nil)
+	if variant.Code != http.StatusOK {
+		t.Fatalf("GET %s?size=thumb = %d, want 200", path, variant.Code)
+	}
+	if got := variant.Header().Get("ETag"); got == etag || !strings.HasPrefix(got, `"`+image.ContentHash+"-") {
+		t.Fatalf("variant ETag = %q", got)
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   int
+		body   int
+	}{
+		{"matching etag", http.Header{"If-None-Match": {etag}}, http.StatusNotModified, 0},
+		{"other etag", http.Header{"If-None-Match": {`"other"`}}, http.StatusOK, size},
+		{"range", http.Header{"Range": {"bytes=0-9"}}, http.StatusPartialContent, 10},
+		{"range with stale if-range", http.Header{"Range": {"bytes=0-9"}, "If-Range": {`"other"`}}, http.StatusOK, size},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, http.MethodGet, path, tt.header)
+			if w.Code != tt.want || w.Body.Len() != tt.body {
+				t.Fatalf("GET %s = %d with %d bytes, want %d with %d bytes", path, w.Code, w.Body.Len(), tt.want, tt.body)
+			}
+		})
+	}
+
+	w = serve(router, http.MethodGet, path, http.Header{"Range": {"bytes=0-9"}})
+	if got, want := w.Header().Get("Content-Range"), "bytes 0-9/"+strconv.Itoa(size); got != want {
+		t.Fatalf("Content-Range = %q, want %q", got, want)
+	}
+}
+
+func TestServeImageMaxAge(t *testing.T) {
+	s := newTestImageService(t)
+	router := newTestImageRouter(s, nil)
+
+	tests := []struct {
+		name     string
+		lifetime time.Duration
+		min, max int
+	}{
+		// max-age 不超过剩余有效期
+		{"short lifetime", time.Minute, 50, 60},
+		{"one hour", time.Hour, 3590, 3600},
+		// 超长有效期按上限缓存
+		{"capped", 3 * 365 * 24 * time.Hour, maxCacheAge, maxCacheAge},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image := s.upload(t, newTestUpload(t, uint8(10+i)), 1)
+			setExpireTime(t, image, time.Now().Add(tt.lifetime))
+
+			w := serve(router, http.MethodGet, "/images/file/"+image.ImageCode, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET /images/file = %d, want 200", w.Code)
+			}
+			cacheControl := w.Header().Get("Cache-Control")
+			maxAge, err := strconv.Atoi(strings.TrimPrefix(cacheControl, "public, max-age="))
+			if err != nil || maxAge < tt.min || maxAge > tt.max {
+				t.Fatalf("Cache-Control = %q, want max-age in [%d, %d]", cacheControl, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestServeImageExpired(t *testing.T) {
+	s := newTestImageService(t)
+	router := newTestImageRouter(s, nil)
+
+	expired := s.upload(t, newTestUpload(t, 20), 1)
+	setExpireTime(t, expired, time.Now().Add(-time.Minute))
+
+	// 已标记过期但文件尚未清理
+	marked := s.upload(t, newTestUpload(t, 21), 1)
+	if err := database.DB.Model(marked).Update("status", "expired").Error; err != nil {
+		t.Fatalf("update status: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		code string
+		want int
+	}{
+		{"past expire time", expired.ImageCode, http.StatusGone},
+		{"expired status", marked.ImageCode, http.StatusGone},
+		{"unknown code", "missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, http.MethodGet, "/images/file/"+tt.code, nil)
+			if w.Code != tt.want {
+				t.Fatalf("GET /images/file/%s = %d, want %d", tt.code, w.Code, tt.want)
+			}
+			if w.Header().Get("ETag") != "" || w.Header().Get("Cache-Control") != "" {
+				t.Fatalf("error response carries cache headers: %v", w.Header())
+			}
+		})
+	}
+}
+
+// setExpireTime 直接修改图片的过期时间
+func setExpireTime(t *testing.T, image *models.Image, expireTime time.Time) {
+	t.Helper()
+	if err := database.DB.Model(image).Update("expire_time", expireTime).Error; err != nil {
+		t.Fatalf("update expire_time: %v", err)
+	}
+}
+
+func TestDeleteImageStatus(t *testing.T) {
+	s := newTestImageService(t)
+	image := s.upload(t, newTestUpload(t, 30), 1)
+	path := "/images/" + strconv.Itoa(image.ID)
+
+	tests := []struct {
+		name   string
+		claims *utils.Claims
+		path   string
+		want   int
+	}{
+		{"invalid id", &utils.Claims{UserID: 1}, "/images/abc", http.StatusBadRequest},
+		{"missing image", &utils.Claims{UserID: 1}, "/images/" + strconv.Itoa(image.ID+1), http.StatusNotFound},
+		{"other user", &utils.Claims{UserID: 2}, path, http.StatusForbidden},
+		{"owner", &utils.Claims{UserID: 1}, path, http.StatusAccepted},
+		{"admin", &utils.Claims{UserID: 9, Roles: []string{models.RoleAdmin}}, path, http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(newTestImageRouter(s, tt.claims), http.MethodDelete, tt.path, nil)
+			if w.Code != tt.want {
+				t.Fatalf("DELETE %s = %d, want %d (%s)", tt.path, w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+
+	// 数据库错误返回500，不暴露内部错误信息
+	database.Close()
+	w := serve(newTestImageRouter(s, &utils.Claims{UserID: 1}), http.MethodDelete, path, nil)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("DELETE with closed database = %d, want 500", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "sql") {
+		t.Fatalf("response leaks the database error: %s", w.Body.String())
+	}
+}
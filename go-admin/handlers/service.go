@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"go-admin/models"
+	"go-admin/utils"
 )
 
 // UserService 用户服务接口
@@ -15,6 +16,9 @@ type UserService interface {
 	Delete(id int) error
 	Authenticate(username, password string) (*models.User, error)
 	UpdateProfile(id int, req models.UpdateProfileRequest) (*models.User, error)
+	LoadPrincipal(id int) (*utils.Principal, error)
+	GetRoles() ([]models.Role, error)
+	AssignRoles(id int, roleNames []string) (*models.User, error)
 }
 
 // MockUserService 模拟用户服务实现
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TaskHandler 后台任务处理器
+type TaskHandler struct {
+	taskService TaskService
+}
+
+// NewTaskHandler 创建后台任务处理器
+func NewTaskHandler(taskService TaskService) *TaskHandler {
+	return &TaskHandler{
+		taskService: taskService,
+	}
+}
+
+// GetTasks 获取任务列表，支持按状态、类型和图片筛选
+func (h *TaskHandler) GetTasks(c *gin.Context) {
+	// 获取分页参数
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	query := TaskQuery{
+		Status:   c.Query("status"),
+		Type:     c.Query("type"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if imageID := c.Query("image_id"); imageID != "" {
+		query.ImageID, err = strconv.Atoi(imageID)
+		if err != nil {
+			utils.BadRequest(c, "无效的图片ID")
+			return
+		}
+	}
+
+	result, err := h.taskService.ListTasks(query, requesterFromContext(c))
+	if err != nil {
+		utils.InternalServerError(c, "获取任务列表失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "获取任务列表成功", result)
+}
+
+// GetTask 获取任务详情
+func (h *TaskHandler) GetTask(c *gin.Context) {
+	task, err := h.taskService.GetTask(c.Param("taskId"), requesterFromContext(c))
+	if err != nil {
+		h.respondError(c, err, "获取任务失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "获取任务成功", task)
+}
+
+// CancelTask 取消等待处理的任务
+func (h *TaskHandler) CancelTask(c *gin.Context) {
+	task, err := h.taskService.CancelTask(c.Param("taskId"), requesterFromContext(c))
+	if err != nil {
+		h.respondError(c, err, "取消任务失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "任务已取消", task)
+}
+
+// GetDeadLetters 获取死信任务列表
+func (h *TaskHandler) GetDeadLetters(c *gin.Context) {
+	// 获取分页参数
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	result, err := h.taskService.ListDeadLetters(page, pageSize)
+	if err != nil {
+		utils.InternalServerError(c, "获取死信任务失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "获取死信任务成功", result)
+}
+
+// GetDeadLetter 获取死信任务详情
+func (h *TaskHandler) GetDeadLetter(c *gin.Context) {
+	task, err := h.taskService.GetDeadLetter(c.Param("taskId"))
+	if err != nil {
+		h.respondError(c, err, "获取死信任务失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "获取死信任务成功", task)
+}
+
+// ReplayDeadLetter 重新执行死信任务
+func (h *TaskHandler) ReplayDeadLetter(c *gin.Context) {
+	if err := h.taskService.ReplayDeadLetter(c.Param("taskId")); err != nil {
+		h.respondError(c, err, "重新执行任务失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "任务已重新加入队列", nil)
+}
+
+// ReplayAllDeadLetters 重新执行全部死信任务
+func (h *TaskHandler) ReplayAllDeadLetters(c *gin.Context) {
+	count, err := h.taskService.ReplayAllDeadLetters()
+	if err != nil {
+		utils.InternalServerError(c, "重新执行任务失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "任务已重新加入队列", gin.H{"replayed": count})
+}
+
+// DeleteDeadLetter 丢弃死信任务
+func (h *TaskHandler) DeleteDeadLetter(c *gin.Context) {
+	if err := h.taskService.DeleteDeadLetter(c.Param("taskId")); err != nil {
+		h.respondError(c, err, "删除死信任务失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "死信任务已删除", nil)
+}
+
+// PurgeDeadLetters 清空死信队列
+func (h *TaskHandler) PurgeDeadLetters(c *gin.Context) {
+	count, err := h.taskService.PurgeDeadLetters()
+	if err != nil {
+		utils.InternalServerError(c, "清空死信队列失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "死信队列已清空", gin.H{"purged": count})
+}
+
+// GetMetrics 获取任务队列指标
+func (h *TaskHandler) GetMetrics(c *gin.Context) {
+	metrics, err := h.taskService.GetMetrics()
+	if err != nil {
+		utils.InternalServerError(c, "获取任务指标失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "获取任务指标成功", metrics)
+}
+
+// respondError 任务不存在时返回404，任务已开始处理时返回409，其余返回500
+func (h *TaskHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrTaskNotFound):
+		utils.NotFound(c, "任务不存在")
+	case errors.Is(err, ErrTaskNotCancellable):
+		utils.Conflict(c, "任务已开始处理，无法取消")
+	default:
+		utils.InternalServerError(c, message)
+	}
+}
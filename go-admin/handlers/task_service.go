@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-admin/config"
+	"go-admin/database"
+	"go-admin/events"
+	"go-admin/jobs"
+	"go-admin/models"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// TaskService 后台任务服务接口
+type TaskService interface {
+	ListTasks(query TaskQuery, requester Requester) (*models.TaskListResponse, error)
+	GetTask(id string, requester Requester) (*models.TaskRecord, error)
+	CancelTask(id string, requester Requester) (*models.Job, error)
+	ListDeadLetters(page, pageSize int) (*models.DeadLetterListResponse, error)
+	GetDeadLetter(id string) (*models.Job, error)
+	ReplayDeadLetter(id string) error
+	ReplayAllDeadLetters() (int, error)
+	DeleteDeadLetter(id string) error
+	PurgeDeadLetters() (int, error)
+	GetMetrics() (*models.TaskMetricsResponse, error)
+}
+
+// TaskQuery 任务列表筛选条件
+type TaskQuery struct {
+	Status   string
+	Type     string
+	ImageID  int
+	Page     int
+	PageSize int
+}
+
+var (
+	// ErrTaskNotFound 任务不存在或无权访问
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrTaskNotCancellable 只有等待处理的任务可以取消
+	ErrTaskNotCancellable = errors.New("task is not pending")
+)
+
+// cancelScript 任务状态未被其他进程修改时写入取消状态，避免与处理进程的领取竞争
+var cancelScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1]) or ''
+if current ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// replayScript 原子地将任务从死信队列移回工作队列，任务已被其他请求处理时返回0
+var replayScript = redis.NewScript(`
+if redis.call('LREM', KEYS[1], 1, ARGV[1]) == 0 then
+	return 0
+end
+redis.call('RPUSH', KEYS[2], ARGV[2])
+return 1
+`)
+
+// TaskServiceImpl 后台任务服务实现
+type TaskServiceImpl struct {
+	redisClient *redis.Client
+	processor   *jobs.Processor
+}
+
+// NewTaskService 创建后台任务服务
+func NewTaskService(redisClient *redis.Client, processor *jobs.Processor) *TaskServiceImpl {
+	return &TaskServiceImpl{
+		redisClient: redisClient,
+		processor:   processor,
+	}
+}
+
+// ListTasks 分页获取任务历史，最近创建的在前，非管理员只能查看自己的任务
+func (s *TaskServiceImpl) ListTasks(query TaskQuery, requester Requester) (*models.TaskListResponse, error) {
+	var records []models.TaskRecord
+	var total int64
+
+	db := database.DB.Model(&models.TaskRecord{}).Scopes(requester.scope)
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.Type != "" {
+		db = db.Where("type = ?", query.Type)
+	}
+	if query.ImageID != 0 {
+		db = db.Where("image_id = ?", query.ImageID)
+	}
+
+	// 获取总数
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	// 分页查询
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Order("created_at DESC").Offset(offset).Limit(query.PageSize).Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.TaskListResponse{
+		Total: int(total),
+		Items: records,
+	}, nil
+}
+
+// GetTask 获取任务详情
+func (s *TaskServiceImpl) GetTask(id string, requester Requester) (*models.TaskRecord, error) {
+	var record models.TaskRecord
+	if err := database.DB.Scopes(requester.scope).First(&record, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// CancelTask 取消等待处理（包括等待重试）的任务。
+// 任务仍留在队列中，处理进程取出后发现已取消会直接丢弃
+func (s *TaskServiceImpl) CancelTask(id string, requester Requester) (*models.Job, error) {
+	ctx := context.Background()
+	record, err := s.GetTask(id, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	// 以Redis中的状态为准，状态已过期时使用历史记录
+	current, err := s.redisClient.Get(ctx, jobs.StatusKey(id)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	job := record.ToJob()
+	if current != "" {
+		job = &models.Job{}
+		if err := job.FromJSON([]byte(current)); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal task: %v", err)
+		}
+	}
+	if job.Status != config.TaskStatusPending {
+		return nil, ErrTaskNotCancellable
+	}
+
+	job.Status = config.TaskStatusCancelled
+	job.NextAttemptAt = nil
+	jobJSON, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal task: %v", err)
+	}
+
+	cancelled, err := cancelScript.Run(ctx, s.redisClient, []string{jobs.StatusKey(id)},
+		current, jobJSON, (24 * time.Hour).Milliseconds()).Int()
+	if err != nil {
+		return nil, err
+	}
+	if cancelled == 0 {
+		// 处理进程已经领取了任务
+		return nil, ErrTaskNotCancellable
+	}
+
+	jobs.SaveRecord(job)
+	events.Publish(ctx, s.redisClient, &models.Event{
+		Type:     models.EventTaskCancelled,
+		UserID:   job.OwnerID,
+		TaskID:   job.ID,
+		TaskType: job.Type,
+		ImageID:  job.ImageID,
+	})
+	return job, nil
+}
+
+// ListDeadLetters 分页获取死信任务，最近失败的在前
+func (s *TaskServiceImpl) ListDeadLetters(page, pageSize int) (*models.DeadLetterListResponse, error) {
+	ctx := context.Background()
+
+	total, err := s.redisClient.LLen(ctx, jobs.DeadLetterQueue).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	start := int64((page - 1) * pageSize)
+	payloads, err := s.redisClient.LRange(ctx, jobs.DeadLetterQueue, start, start+int64(pageSize)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.Job, 0, len(payloads))
+	for _, payload := range payloads {
+		var task models.Job
+		if err := task.FromJSON([]byte(payload)); err != nil {
+			continue
+		}
+		items = append(items, task)
+	}
+
+	return &models.DeadLetterListResponse{
+		Total: int(total),
+		Items: items,
+	}, nil
+}
+
+// GetDeadLetter 获取死信任务详情
+func (s *TaskServiceImpl) GetDeadLetter(id string) (*models.Job, error) {
+	task, _, err := s.findDeadLetter(context.Background(), id)
+	return task, err
+}
+
+// ReplayDeadLetter 重新执行单个死信任务
+func (s *TaskServiceImpl) ReplayDeadLetter(id string) error {
+	ctx := context.Background()
+	task, payload, err := s.findDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	replayed, err := s.replay(ctx, task, payload)
+	if err != nil {
+		return err
+	}
+	if !replayed {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// ReplayAllDeadLetters 重新执行全部死信任务
+func (s *TaskServiceImpl) ReplayAllDeadLetters() (int, error) {
+	ctx := context.Background()
+	payloads, err := s.redisClient.LRange(ctx, jobs.DeadLetterQueue, 0, -1).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, payload := range payloads {
+		var task models.Job
+		if err := task.FromJSON([]byte(payload)); err != nil {
+			continue
+		}
+		replayed, err := s.replay(ctx, &task, payload)
+		if err != nil {
+			return count, err
+		}
+		if replayed {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteDeadLetter 丢弃单个死信任务
+func (s *TaskServiceImpl) DeleteDeadLetter(id string) error {
+	ctx := context.Background()
+	_, payload, err := s.findDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.redisClient.LRem(ctx, jobs.DeadLetterQueue, 1, payload).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// PurgeDeadLetters 清空死信队列
+func (s *TaskServiceImpl) PurgeDeadLetters() (int, error) {
+	ctx := context.Background()
+
+	var length *redis.IntCmd
+	_, err := s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		length = pipe.LLen(ctx, jobs.DeadLetterQueue)
+		pipe.Del(ctx, jobs.DeadLetterQueue)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(length.Val()), nil
+}
+
+// GetMetrics 获取各队列的积压数量和本进程的处理计数
+func (s *TaskServiceImpl) GetMetrics() (*models.TaskMetricsResponse, error) {
+	ctx := context.Background()
+	queues := s.processor.Metrics()
+
+	pipe := s.redisClient.Pipeline()
+	pending := make([]*redis.IntCmd, len(queues))
+	for i, queue := range queues {
+		pending[i] = pipe.LLen(ctx, queue.Queue)
+	}
+	retrying := pipe.ZCard(ctx, jobs.RetryQueue)
+	deadLetters := pipe.LLen(ctx, jobs.DeadLetterQueue)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	for i := range queues {
+		queues[i].Pending = pending[i].Val()
+	}
+
+	return &models.TaskMetricsResponse{
+		Queues:      queues,
+		Retrying:    retrying.Val(),
+		DeadLetters: deadLetters.Val(),
+	}, nil
+}
+
+// findDeadLetter 在死信队列中查找任务，同时返回原始内容用于移除
+func (s *TaskServiceImpl) findDeadLetter(ctx context.Context, id string) (*models.Job, string, error) {
+	payloads, err := s.redisClient.LRange(ctx, jobs.DeadLetterQueue, 0, -1).Result()
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, payload := range payloads {
+		var task models.Job
+		if err := task.FromJSON([]byte(payload)); err != nil {
+			continue
+		}
+		if task.ID == id {
+			return &task, payload, nil
+		}
+	}
+	return nil, "", ErrTaskNotFound
+}
+
+// replay 重置重试次数后将任务放回对应的工作队列
+func (s *TaskServiceImpl) replay(ctx context.Context, task *models.Job, payload string) (bool, error) {
+	task.RetryCount = 0
+	task.FailedAt = nil
+	task.NextAttemptAt = nil
+	task.Status = config.TaskStatusPending
+	taskJSON, err := json.Marshal(task)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal task: %v", err)
+	}
+
+	definition, ok := s.processor.Registry().Lookup(task.Type)
+	if !ok {
+		return false, fmt.Errorf("%w: %s", jobs.ErrUnknownJobType, task.Type)
+	}
+
+	replayed, err := replayScript.Run(ctx, s.redisClient,
+		[]string{jobs.DeadLetterQueue, definition.Queue},
+		payload, taskJSON).Int()
+	if err != nil {
+		return false, err
+	}
+	if replayed == 0 {
+		return false, nil
+	}
+
+	s.redisClient.Set(ctx, jobs.StatusKey(task.ID), taskJSON, 24*time.Hour)
+	jobs.SaveRecord(task)
+	return true, nil
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"go-admin/config"
+	"go-admin/jobs"
+	"go-admin/models"
+	"go-admin/testutil"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const testTaskQueue = "test:queue"
+
+// testTaskService 测试使用的任务服务
+type testTaskService struct {
+	*TaskServiceImpl
+	redisClient *redis.Client
+}
+
+// newTestTaskService 创建使用临时数据库和内存Redis的任务服务，注册 test 类型的任务
+func newTestTaskService(t *testing.T) *testTaskService {
+	t.Helper()
+	cfg := testutil.Config(t)
+	testutil.NewDB(t, cfg)
+	_, client := testutil.NewRedis(t)
+
+	registry := jobs.NewRegistry()
+	registry.Register(jobs.Definition{Type: "test", Queue: testTaskQueue})
+	processor := jobs.NewProcessor(client, registry, config.NewStore(cfg, nil))
+	return &testTaskService{
+		TaskServiceImpl: NewTaskService(client, processor),
+		redisClient:     client,
+	}
+}
+
+// saveTask 以指定状态保存 ownerID 的任务，写入Redis状态和历史记录
+func (s *testTaskService) saveTask(t *testing.T, ownerID int, status string) *models.Job {
+	t.Helper()
+	job, err := models.NewJob("test", map[string]int{"n": 1})
+	if err != nil {
+		t.Fatalf("NewJob: %v", err)
+	}
+	job.OwnerID = ownerID
+	job.Status = status
+	data, _ := json.Marshal(job)
+	if err := s.redisClient.Set(context.Background(), jobs.StatusKey(job.ID), data, time.Hour).Err(); err != nil {
+		t.Fatalf("set status: %v", err)
+	}
+	jobs.SaveRecord(job)
+	return job
+}
+
+// deadLetter 将重试耗尽的任务放入死信队列
+func (s *testTaskService) deadLetter(t *testing.T) *models.Job {
+	t.Helper()
+	job := s.saveTask(t, 1, config.TaskStatusFailed)
+	now := time.Now()
+	job.RetryCount = 4
+	job.LastError = "boom"
+	job.FailedAt = &now
+	data, _ := json.Marshal(job)
+	if err := s.redisClient.RPush(context.Background(), jobs.DeadLetterQueue, data).Err(); err != nil {
+		t.Fatalf("push dead letter: %v", err)
+	}
+	return job
+}
+
+// queued 返回工作队列中的任务
+func (s *testTaskService) queued(t *testing.T) []models.Job {
+	t.Helper()
+	payloads, err := s.redisClient.LRange(context.Background(), testTaskQueue, 0, -1).Result()
+	if err != nil {
+		t.Fatalf("read queue: %v", err)
+	}
+	queued := make([]models.Job, len(payloads))
+	for i, payload := range payloads {
+		if err := queued[i].FromJSON([]byte(payload)); err != nil {
+			t.Fatalf("decode queued job: %v", err)
+		}
+	}
+	return queued
+}
+
+// deadLetterCount 返回死信队列长度
+func (s *testTaskService) deadLetterCount(t *testing.T) int64 {
+	t.Helper()
+	n, err := s.redisClient.LLen(context.Background(), jobs.DeadLetterQueue).Result()
+	if err != nil {
+		t.Fatalf("LLen: %v", err)
+	}
+	return n
+}
+
+func TestReplayDeadLetter(t *testing.T) {
+	s := newTestTaskService(t)
+	job := s.deadLetter(t)
+	other := s.deadLetter(t)
+
+	// 并发重放同一任务，只有一次成功
+	const replays = 8
+	errs := make([]error, replays)
+	var wg sync.WaitGroup
+	for i := 0; i < replays; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = s.ReplayDeadLetter(job.ID)
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrTaskNotFound):
+			t.Fatalf("ReplayDeadLetter: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("%d replays succeeded, want 1", succeeded)
+	}
+
+	queued := s.queued(t)
+	if len(queued) != 1 || queued[0].ID != job.ID {
+		t.Fatalf("queue = %+v, want only %s", queued, job.ID)
+	}
+	if queued[0].Status != config.TaskStatusPending || queued[0].RetryCount != 0 || queued[0].FailedAt != nil {
+		t.Fatalf("replayed job = %+v, want pending with retries reset", queued[0])
+	}
+	if n := s.deadLetterCount(t); n != 1 {
+		t.Fatalf("dead letter queue has %d jobs, want 1", n)
+	}
+	if record, err := s.GetTask(job.ID, Requester{IsAdmin: true}); err != nil || record.Status != config.TaskStatusPending {
+		t.Fatalf("record = %+v, %v; want pending", record, err)
+	}
+
+	if _, err := s.GetDeadLetter(other.ID); err != nil {
+		t.Fatalf("GetDeadLetter(other): %v", err)
+	}
+	if err := s.ReplayDeadLetter("missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("ReplayDeadLetter(missing) = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestReplayAllDeadLetters(t *testing.T) {
+	s := newTestTaskService(t)
+	for i := 0; i < 3; i++ {
+		s.deadLetter(t)
+	}
+
+	count, err := s.ReplayAllDeadLetters()
+	if err != nil || count != 3 {
+		t.Fatalf("ReplayAllDeadLetters = %d, %v; want 3", count, err)
+	}
+	if n := s.deadLetterCount(t); n != 0 {
+		t.Fatalf("dead letter queue has %d jobs, want 0", n)
+	}
+
+	// 再次重放不会重复入队
+	if count, err := s.ReplayAllDeadLetters(); err != nil || count != 0 {
+		t.Fatalf("second ReplayAllDeadLetters = %d, %v; want 0", count, err)
+	}
+	if queued := s.queued(t); len(queued) != 3 {
+		t.Fatalf("queue has %d jobs, want 3", len(queued))
+	}
+}
+
+func TestDeleteDeadLetter(t *testing.T) {
+	s := newTestTaskService(t)
+	job := s.deadLetter(t)
+	kept := s.deadLetter(t)
+
+	if err := s.DeleteDeadLetter(job.ID); err != nil {
+		t.Fatalf("DeleteDeadLetter: %v", err)
+	}
+	if err := s.DeleteDeadLetter(job.ID); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("second DeleteDeadLetter = %v, want ErrTaskNotFound", err)
+	}
+	if err := s.ReplayDeadLetter(job.ID); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("ReplayDeadLetter after delete = %v, want ErrTaskNotFound", err)
+	}
+
+	list, err := s.ListDeadLetters(1, 10)
+	if err != nil {
+		t.Fatalf("ListDeadLetters: %v", err)
+	}
+	if list.Total != 1 || len(list.Items) != 1 || list.Items[0].ID != kept.ID {
+		t.Fatalf("dead letters = %+v, want only %s", list, kept.ID)
+	}
+	if queued := s.queued(t); len(queued) != 0 {
+		t.Fatalf("queue has %d jobs, want 0", len(queued))
+	}
+}
+
+func TestPurgeDeadLetters(t *testing.T) {
+	s := newTestTaskService(t)
+	for i := 0; i < 3; i++ {
+		s.deadLetter(t)
+	}
+
+	if count, err := s.PurgeDeadLetters(); err != nil || count != 3 {
+		t.Fatalf("PurgeDeadLetters = %d, %v; want 3", count, err)
+	}
+	if n := s.deadLetterCount(t); n != 0 {
+		t.Fatalf("dead letter queue has %d jobs after purge, want 0", n)
+	}
+	if count, err := s.PurgeDeadLetters(); err != nil || count != 0 {
+		t.Fatalf("second PurgeDeadLetters = %d, %v; want 0", count, err)
+	}
+	if queued := s.queued(t); len(queued) != 0 {
+		t.Fatalf("purge queued %d jobs", len(queued))
+	}
+}
+
+func TestCancelTask(t *testing.T) {
+	tests := []struct {
+		status    string
+		requester Requester
+		want      error
+	}{
+		{config.TaskStatusPending, Requester{UserID: 1}, nil},
+		{config.TaskStatusPending, Requester{UserID: 2, IsAdmin: true}, nil},
+		// 其他用户的任务与不存在的任务返回相同的错误
+		{config.TaskStatusPending, Requester{UserID: 2}, ErrTaskNotFound},
+		{config.TaskStatusProcessing, Requester{UserID: 1}, ErrTaskNotCancellable},
+		{config.TaskStatusCompleted, Requester{UserID: 1}, ErrTaskNotCancellable},
+		{config.TaskStatusCancelled, Requester{UserID: 1}, ErrTaskNotCancellable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			s := newTestTaskService(t)
+			job := s.saveTask(t, 1, tt.status)
+
+			cancelled, err := s.CancelTask(job.ID, tt.requester)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("CancelTask = %v, want %v", err, tt.want)
+			}
+
+			status := tt.status
+			if tt.want == nil {
+				status = config.TaskStatusCancelled
+				if cancelled.Status != status {
+					t.Fatalf("cancelled task status = %s", cancelled.Status)
+				}
+			}
+			data, _ := s.redisClient.Get(context.Background(), jobs.StatusKey(job.ID)).Bytes()
+			var current models.Job
+			if err := current.FromJSON(data); err != nil || current.Status != status {
+				t.Fatalf("status = %s, %v; want %s", current.Status, err, status)
+			}
+		})
+	}
+}
+
+func TestCancelScriptRefusesClaimedTask(t *testing.T) {
+	s := newTestTaskService(t)
+	ctx := context.Background()
+	job := s.saveTask(t, 1, config.TaskStatusPending)
+	pending, _ := s.redisClient.Get(ctx, jobs.StatusKey(job.ID)).Result()
+
+	// 读取状态之后处理进程领取了任务
+	job.Status = config.TaskStatusProcessing
+	processing, _ := json.Marshal(job)
+	s.redisClient.Set(ctx, jobs.StatusKey(job.ID), processing, time.Hour)
+
+	cancelled, err := cancelScript.Run(ctx, s.redisClient, []string{jobs.StatusKey(job.ID)},
+		pending, `{"status":"cancelled"}`, time.Hour.Milliseconds()).Int()
+	if err != nil || cancelled != 0 {
+		t.Fatalf("cancelScript = %d, %v; want 0", cancelled, err)
+	}
+	if current, _ := s.redisClient.Get(ctx, jobs.StatusKey(job.ID)).Result(); current != string(processing) {
+		t.Fatalf("status overwritten: %s", current)
+	}
+}
+
+func TestCancelTaskHTTPStatus(t *testing.T) {
+	s := newTestTaskService(t)
+	pending := s.saveTask(t, 1, config.TaskStatusPending)
+	processing := s.saveTask(t, 1, config.TaskStatusProcessing)
+
+	handler := NewTaskHandler(s.TaskServiceImpl)
+	router := gin.New()
+	router.POST("/tasks/:taskId/cancel", func(c *gin.Context) {
+		c.Set("claims", &utils.Claims{UserID: 1})
+		c.Next()
+	}, handler.CancelTask)
+
+	tests := []struct {
+		id   string
+		want int
+	}{
+		{pending.ID, http.StatusOK},
+		// 已开始处理的任务按文档返回409
+		{processing.ID, http.StatusConflict},
+		{"missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := serve(router, http.MethodPost, "/tasks/"+tt.id+"/cancel", nil); w.Code != tt.want {
+			t.Errorf("cancel %s = %d, want %d", tt.id, w.Code, tt.want)
+		}
+	}
+}
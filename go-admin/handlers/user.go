@@ -132,4 +132,38 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 
 	utils.SuccessWithMessage(c, "Profile updated successfully", user.ToResponse())
 }
- 
\ No newline at end of file
+ 
+// GetRoles 获取角色列表
+func (h *UserHandler) GetRoles(c *gin.Context) {
+	roles, err := h.userService.GetRoles()
+	if err != nil {
+		utils.InternalServerError(c, "Failed to get roles")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "Roles retrieved successfully", roles)
+}
+
+// AssignRoles 设置用户角色
+func (h *UserHandler) AssignRoles(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	var req models.AssignRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	user, err := h.userService.AssignRoles(id, req.Roles)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "User roles updated successfully", user.ToResponse())
+}
@@ -2,30 +2,44 @@ package handlers
 
 import (
 	"errors"
+	"log"
+	"sync"
 
 	"go-admin/database"
 	"go-admin/models"
+	"go-admin/utils"
+
+	"gorm.io/gorm"
 )
 
 // UserServiceImpl 用户服务实现
-type UserServiceImpl struct{}
+type UserServiceImpl struct {
+	hasher     utils.PasswordHasher
+	jwtManager *utils.JWTManager
+
+	dummyOnce sync.Once
+	dummyHash string // 用户不存在时用于校验的哈希
+}
 
 // NewUserService 创建用户服务
-func NewUserService() *UserServiceImpl {
-	return &UserServiceImpl{}
+func NewUserService(hasher utils.PasswordHasher, jwtManager *utils.JWTManager) *UserServiceImpl {
+	return &UserServiceImpl{
+		hasher:     hasher,
+		jwtManager: jwtManager,
+	}
 }
 
 // GetAll 获取所有用户
 func (s *UserServiceImpl) GetAll() ([]models.User, error) {
 	var users []models.User
-	err := database.DB.Find(&users).Error
+	err := database.DB.Preload("Roles").Find(&users).Error
 	return users, err
 }
 
 // GetByID 根据ID获取用户
 func (s *UserServiceImpl) GetByID(id int) (*models.User, error) {
 	var user models.User
-	err := database.DB.First(&user, id).Error
+	err := database.DB.Preload("Roles").First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -45,18 +59,28 @@ func (s *UserServiceImpl) Create(req models.CreateUserRequest) (*models.User, er
 		return nil, errors.New("email already exists")
 	}
 
+	hashedPassword, err := s.hasher.Hash(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
 	user := models.User{
 		Username: req.Username,
-		Password: req.Password, // 实际项目中应该加密
+		Password: hashedPassword,
 		Email:    req.Email,
 		Status:   req.Status,
 	}
 
-	err := database.DB.Create(&user).Error
+	err = database.DB.Create(&user).Error
 	if err != nil {
 		return nil, err
 	}
 
+	// 新用户默认为普通成员
+	if err := database.AssignRoles(&user, []string{models.RoleMember}); err != nil {
+		return nil, err
+	}
+
 	return &user, nil
 }
 
@@ -97,14 +121,31 @@ func (s *UserServiceImpl) Delete(id int) error {
 		return errors.New("user not found")
 	}
 
-	return database.DB.Delete(&user).Error
+	// 先吊销会话，已签发的令牌不能继续使用
+	if err := s.jwtManager.RevokeAllSessions(user.ID); err != nil {
+		return err
+	}
+
+	// user_roles 对 users 有外键约束，先清除角色关联
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&user).Error
+	})
 }
 
 // Authenticate 用户认证
 func (s *UserServiceImpl) Authenticate(username, password string) (*models.User, error) {
 	var user models.User
-	err := database.DB.Where("username = ? AND password = ?", username, password).First(&user).Error
-	if err != nil {
+	if err := database.DB.Preload("Roles").Where("username = ?", username).First(&user).Error; err != nil {
+		// 用户不存在时同样执行一次哈希校验，避免通过响应时间判断用户名是否存在
+		s.hasher.Verify(s.notFoundHash(), password)
+		return nil, errors.New("invalid credentials")
+	}
+
+	ok, err := s.hasher.Verify(user.Password, password)
+	if err != nil || !ok {
 		return nil, errors.New("invalid credentials")
 	}
 
@@ -112,9 +153,31 @@ func (s *UserServiceImpl) Authenticate(username, password string) (*models.User,
 		return nil, errors.New("user account is disabled")
 	}
 
+	// 明文或旧参数的密码在登录成功后透明升级
+	if s.hasher.NeedsRehash(user.Password) {
+		if hashedPassword, err := s.hasher.Hash(password); err != nil {
+			log.Printf("Failed to rehash password for user %d: %v", user.ID, err)
+		} else if err := database.DB.Model(&user).Update("password", hashedPassword).Error; err != nil {
+			log.Printf("Failed to upgrade password hash for user %d: %v", user.ID, err)
+		}
+	}
+
 	return &user, nil
 }
 
+// notFoundHash 与真实密码使用相同参数的固定哈希，首次使用时生成
+func (s *UserServiceImpl) notFoundHash() string {
+	s.dummyOnce.Do(func() {
+		hashed, err := s.hasher.Hash("invalid-credentials-placeholder")
+		if err != nil {
+			log.Printf("Failed to generate placeholder password hash: %v", err)
+			return
+		}
+		s.dummyHash = hashed
+	})
+	return s.dummyHash
+}
+
 // UpdateProfile 更新用户个人信息
 func (s *UserServiceImpl) UpdateProfile(id int, req models.UpdateProfileRequest) (*models.User, error) {
 	var user models.User
@@ -142,7 +205,11 @@ func (s *UserServiceImpl) UpdateProfile(id int, req models.UpdateProfileRequest)
 
 	// 更新密码
 	if req.Password != "" {
-		user.Password = req.Password // 实际项目中应该加密
+		hashedPassword, err := s.hasher.Hash(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = hashedPassword
 	}
 
 	err := database.DB.Save(&user).Error
@@ -152,3 +219,43 @@ func (s *UserServiceImpl) UpdateProfile(id int, req models.UpdateProfileRequest)
 
 	return &user, nil
 }
+
+// LoadPrincipal 加载用户身份及其角色权限，用于签发令牌
+func (s *UserServiceImpl) LoadPrincipal(id int) (*utils.Principal, error) {
+	var user models.User
+	if err := database.DB.Preload("Roles.Permissions").First(&user, id).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.Status != "active" {
+		return nil, errors.New("user account is disabled")
+	}
+
+	return &utils.Principal{
+		UserID:      user.ID,
+		Username:    user.Username,
+		Roles:       user.RoleNames(),
+		Permissions: user.PermissionCodes(),
+	}, nil
+}
+
+// GetRoles 获取所有角色
+func (s *UserServiceImpl) GetRoles() ([]models.Role, error) {
+	var roles []models.Role
+	err := database.DB.Preload("Permissions").Order("id").Find(&roles).Error
+	return roles, err
+}
+
+// AssignRoles 设置用户角色
+func (s *UserServiceImpl) AssignRoles(id int, roleNames []string) (*models.User, error) {
+	var user models.User
+	if err := database.DB.First(&user, id).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if err := database.AssignRoles(&user, roleNames); err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(id)
+}
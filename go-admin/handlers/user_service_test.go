@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-admin/database"
+	"go-admin/models"
+	"go-admin/testutil"
+	"go-admin/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// countingHasher 记录校验次数的哈希器
+type countingHasher struct {
+	*utils.BcryptHasher
+	verifies atomic.Int32
+}
+
+func (h *countingHasher) Verify(stored, password string) (bool, error) {
+	h.verifies.Add(1)
+	return h.BcryptHasher.Verify(stored, password)
+}
+
+// newTestUserService 创建使用临时数据库的用户服务，jwtManager 可以为nil
+func newTestUserService(t *testing.T, hasher utils.PasswordHasher, jwtManager *utils.JWTManager) *UserServiceImpl {
+	t.Helper()
+	testutil.NewDB(t, testutil.Config(t))
+	if err := database.SeedRBAC(); err != nil {
+		t.Fatalf("SeedRBAC: %v", err)
+	}
+	return NewUserService(hasher, jwtManager)
+}
+
+// createUserWithPassword 直接写入存储的密码值，模拟历史数据
+func createUserWithPassword(t *testing.T, username, stored string) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, Password: stored, Email: username + "@example.com", Status: "active"}
+	if err := database.DB.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// storedPassword 读取数据库中的密码值
+func storedPassword(t *testing.T, id int) string {
+	t.Helper()
+	var user models.User
+	if err := database.DB.First(&user, id).Error; err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	return user.Password
+}
+
+func TestAuthenticateUpgradesLegacyPassword(t *testing.T) {
+	hasher := utils.NewBcryptHasher(bcrypt.MinCost + 1)
+	service := newTestUserService(t, hasher, nil)
+	weak, err := utils.NewBcryptHasher(bcrypt.MinCost).Hash("user123")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	tests := []struct {
+		username string
+		stored   string
+		password string
+	}{
+		{"plaintext", "admin123", "admin123"},
+		{"lowcost", weak, "user123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			password := tt.password
+			user := createUserWithPassword(t, tt.username, tt.stored)
+
+			if _, err := service.Authenticate(user.Username, password+"x"); err == nil {
+				t.Fatalf("Authenticate with wrong password succeeded")
+			}
+			if got := storedPassword(t, user.ID); got != tt.stored {
+				t.Fatalf("failed login changed the stored password")
+			}
+
+			if _, err := service.Authenticate(user.Username, password); err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			upgraded := storedPassword(t, user.ID)
+			if upgraded == tt.stored || hasher.NeedsRehash(upgraded) {
+				t.Fatalf("stored password %q was not upgraded", upgraded)
+			}
+			if ok, _ := hasher.Verify(upgraded, password); !ok {
+				t.Fatalf("upgraded hash does not match the password")
+			}
+
+			// 升级后的哈希可以继续登录，且不会再次重写
+			if _, err := service.Authenticate(user.Username, password); err != nil {
+				t.Fatalf("Authenticate after upgrade: %v", err)
+			}
+			if got := storedPassword(t, user.ID); got != upgraded {
+				t.Fatalf("stored password rewritten on second login")
+			}
+		})
+	}
+}
+
+func TestAuthenticateUnknownUserVerifiesPassword(t *testing.T) {
+	hasher := &countingHasher{BcryptHasher: utils.NewBcryptHasher(bcrypt.MinCost)}
+	service := newTestUserService(t, hasher, nil)
+
+	// 用户不存在时同样执行一次bcrypt校验，与密码错误的耗时一致
+	for i := 1; i <= 2; i++ {
+		if _, err := service.Authenticate("nobody", "secret123"); err == nil || err.Error() != "invalid credentials" {
+			t.Fatalf("Authenticate(unknown) = %v, want invalid credentials", err)
+		}
+		if got := hasher.verifies.Load(); got != int32(i) {
+			t.Fatalf("Verify called %d times after %d attempts, want %d", got, i, i)
+		}
+	}
+	if cost, err := bcrypt.Cost([]byte(service.dummyHash)); err != nil || cost != bcrypt.MinCost {
+		t.Fatalf("placeholder hash cost = %d, %v, want %d", cost, err, bcrypt.MinCost)
+	}
+}
+
+func TestDeleteUserWithRolesAndSessions(t *testing.T) {
+	_, client := testutil.NewRedis(t)
+	jwtManager := utils.NewJWTManager("test-secret", 15*time.Minute, time.Hour, client)
+	service := newTestUserService(t, utils.NewBcryptHasher(bcrypt.MinCost), jwtManager)
+
+	user, err := service.Create(models.CreateUserRequest{
+		Username: "alice",
+		Password: "secret123",
+		Email:    "alice@example.com",
+		Status:   "active",
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	principal, err := service.LoadPrincipal(user.ID)
+	if err != nil {
+		t.Fatalf("LoadPrincipal: %v", err)
+	}
+	tokens, err := jwtManager.GenerateTokenPair(principal)
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+
+	if err := service.Delete(user.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	var roles int64
+	database.DB.Table("user_roles").Where("user_id = ?", user.ID).Count(&roles)
+	if roles != 0 {
+		t.Fatalf("%d role assignments left, want 0", roles)
+	}
+	if _, err := service.GetByID(user.ID); err == nil {
+		t.Fatalf("GetByID after delete succeeded")
+	}
+	if _, err := jwtManager.ValidateToken(tokens.AccessToken); !errors.Is(err, utils.ErrTokenRevoked) {
+		t.Fatalf("ValidateToken after delete = %v, want ErrTokenRevoked", err)
+	}
+}
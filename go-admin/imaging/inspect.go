@@ -0,0 +1,129 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // 注册GIF解码器
+	_ "image/jpeg" // 注册JPEG解码器
+	_ "image/png"  // 注册PNG解码器
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type, only support jpg, jpeg, png, gif")
+	ErrTypeMismatch    = errors.New("file extension does not match image content")
+	ErrCorruptImage    = errors.New("image file is corrupt or truncated")
+	ErrTooLarge        = errors.New("image dimensions exceed the allowed limit")
+)
+
+// 支持的格式：MIME类型及允许的扩展名
+var formats = map[string]struct {
+	mimeType   string
+	extensions []string
+}{
+	"jpeg": {"image/jpeg", []string{".jpg", ".jpeg"}},
+	"png":  {"image/png", []string{".png"}},
+	"gif":  {"image/gif", []string{".gif"}},
+}
+
+// Limits 图片格式和尺寸限制，尺寸限制用于防止解压炸弹
+type Limits struct {
+	Formats   []string // 允许的格式，为空时允许全部支持的格式
+	MaxWidth  int
+	MaxHeight int
+	MaxPixels int64
+}
+
+// Info 图片检测结果
+type Info struct {
+	Format   string // jpeg, png, gif
+	MimeType string
+	Width    int
+	Height   int
+}
+
+// Inspect 通过魔数和解码校验图片，而不是只看扩展名，返回检测结果和解码后的图片
+func Inspect(r io.ReadSeeker, filename string, limits Limits) (*Info, image.Image, error) {
+	// 嗅探魔数
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, nil, ErrCorruptImage
+	}
+	mimeType := http.DetectContentType(head[:n])
+
+	// 只解码头部获取尺寸
+	config, format, err := image.DecodeConfig(io.MultiReader(bytes.NewReader(head[:n]), r))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, nil, ErrUnsupportedType
+		}
+		return nil, nil, ErrCorruptImage
+	}
+
+	expected, ok := formats[format]
+	if !ok || !limits.allows(format) {
+		return nil, nil, ErrUnsupportedType
+	}
+	if mimeType != expected.mimeType {
+		return nil, nil, ErrTypeMismatch
+	}
+	if !hasExtension(filename, expected.extensions) {
+		return nil, nil, ErrTypeMismatch
+	}
+
+	// 尺寸限制必须在完整解码之前检查
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, nil, ErrCorruptImage
+	}
+	if (limits.MaxWidth > 0 && config.Width > limits.MaxWidth) ||
+		(limits.MaxHeight > 0 && config.Height > limits.MaxHeight) ||
+		(limits.MaxPixels > 0 && int64(config.Width)*int64(config.Height) > limits.MaxPixels) {
+		return nil, nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, config.Width, config.Height)
+	}
+
+	// 完整解码一次，拒绝截断或损坏的文件
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, nil, ErrCorruptImage
+	}
+
+	return &Info{
+		Format:   format,
+		MimeType: expected.mimeType,
+		Width:    config.Width,
+		Height:   config.Height,
+	}, img, nil
+}
+
+// allows 是否允许该格式
+func (l Limits) allows(format string) bool {
+	if len(l.Formats) == 0 {
+		return true
+	}
+	for _, f := range l.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// hasExtension 判断文件扩展名是否属于该格式
+func hasExtension(filename string, extensions []string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, e := range extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// testImage 生成指定尺寸的渐变图片
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+// encodeTestImage 按格式编码指定尺寸的图片
+func encodeTestImage(t *testing.T, format string, width, height int) []byte {
+	t.Helper()
+	data, err := EncodeBytes(testImage(width, height), format, 0)
+	if err != nil {
+		t.Fatalf("encode %s: %v", format, err)
+	}
+	return data
+}
+
+// withPNGSize 修改PNG头部声明的宽高并重新计算校验和，像素数据保持不变
+func withPNGSize(t *testing.T, data []byte, width, height uint32) []byte {
+	t.Helper()
+	patched := bytes.Clone(data)
+	// 8字节签名之后是IHDR块：长度(4) 类型(4) 宽(4) 高(4) ... CRC(4)
+	const ihdr = 8
+	if string(patched[ihdr+4:ihdr+8]) != "IHDR" {
+		t.Fatalf("unexpected PNG layout")
+	}
+	binary.BigEndian.PutUint32(patched[ihdr+8:], width)
+	binary.BigEndian.PutUint32(patched[ihdr+12:], height)
+	length := binary.BigEndian.Uint32(patched[ihdr:])
+	crc := crc32.ChecksumIEEE(patched[ihdr+4 : ihdr+8+int(length)])
+	binary.BigEndian.PutUint32(patched[ihdr+8+int(length):], crc)
+	return patched
+}
+
+func TestInspect(t *testing.T) {
+	png := encodeTestImage(t, "png", 40, 30)
+	jpeg := encodeTestImage(t, "jpeg", 40, 30)
+	gif := encodeTestImage(t, "gif", 40, 30)
+	limits := Limits{MaxWidth: 100, MaxHeight: 80, MaxPixels: 4000}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		filename string
+		limits   Limits
+		want     error
+		format   string
+	}{
+		{"png", png, "a.png", limits, nil, "png"},
+		{"jpeg as jpg", jpeg, "a.jpg", limits, nil, "jpeg"},
+		{"jpeg as upper case JPEG", jpeg, "a.JPEG", limits, nil, "jpeg"},
+		{"gif", gif, "a.gif", limits, nil, "gif"},
+		{"no limits", png, "a.png", Limits{}, nil, "png"},
+
+		// 扩展名与内容不符
+		{"png named jpg", png, "a.jpg", limits, ErrTypeMismatch, ""},
+		{"jpeg named png", jpeg, "a.png", limits, ErrTypeMismatch, ""},
+		{"gif named png", gif, "a.png", limits, ErrTypeMismatch, ""},
+		{"png without extension", png, "a", limits, ErrTypeMismatch, ""},
+		{"png with double extension", png, "a.png.exe", limits, ErrTypeMismatch, ""},
+
+		// 不是支持的图片
+		{"text named png", []byte("<html><body>hello</body></html>"), "a.png", limits, ErrUnsupportedType, ""},
+		{"format not allowed", png, "a.png", Limits{Formats: []string{"jpeg", "gif"}}, ErrUnsupportedType, ""},
+		{"empty file", nil, "a.png", limits, ErrCorruptImage, ""},
+		{"truncated png", png[:len(png)/2], "a.png", limits, ErrCorruptImage, ""},
+
+		// 尺寸限制
+		{"too wide", encodeTestImage(t, "png", 101, 10), "a.png", limits, ErrTooLarge, ""},
+		{"too tall", encodeTestImage(t, "png", 10, 81), "a.png", limits, ErrTooLarge, ""},
+		{"too many pixels", encodeTestImage(t, "png", 80, 60), "a.png", limits, ErrTooLarge, ""},
+		{"at the limits", encodeTestImage(t, "png", 100, 40), "a.png", limits, nil, "png"},
+		// 只有头部声明了巨大的尺寸，必须在解码像素之前拒绝
+		{"decompression bomb header", withPNGSize(t, png, 100000, 100000), "a.png", limits, ErrTooLarge, ""},
+		{"zero width header", withPNGSize(t, png, 0, 30), "a.png", limits, ErrCorruptImage, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, img, err := Inspect(bytes.NewReader(tt.data), tt.filename, tt.limits)
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("Inspect = %v, want %v", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Inspect: %v", err)
+			}
+			if info.Format != tt.format || info.MimeType != MimeType(tt.format) {
+				t.Fatalf("Inspect = %+v, want format %s", info, tt.format)
+			}
+			if bounds := img.Bounds(); bounds.Dx() != info.Width || bounds.Dy() != info.Height {
+				t.Fatalf("decoded %v, info %dx%d", bounds, info.Width, info.Height)
+			}
+		})
+	}
+}
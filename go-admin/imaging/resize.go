@@ -0,0 +1,107 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+)
+
+// DefaultJPEGQuality 默认JPEG编码质量
+const DefaultJPEGQuality = 85
+
+// Fit 将图片等比缩放到 maxSize×maxSize 以内，不放大
+func Fit(src image.Image, maxSize int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSize && height <= maxSize {
+		return src
+	}
+
+	if width >= height {
+		height = max(1, height*maxSize/width)
+		width = maxSize
+	} else {
+		width = max(1, width*maxSize/height)
+		height = maxSize
+	}
+
+	return scale(src, width, height)
+}
+
+// scale 使用CatmullRom插值缩放到指定尺寸
+func scale(src image.Image, width, height int) image.Image {
+	return scaleRect(src, src.Bounds(), width, height)
+}
+
+// scaleRect 将原图的指定区域缩放到指定尺寸
+func scaleRect(src image.Image, srcRect image.Rectangle, width, height int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, srcRect, draw.Over, nil)
+	return dst
+}
+
+// Decode 解码图片
+func Decode(r io.Reader) (image.Image, string, error) {
+	return image.Decode(r)
+}
+
+// Encode 按格式编码图片，quality只对JPEG生效
+func Encode(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case "jpeg":
+		if quality <= 0 {
+			quality = DefaultJPEGQuality
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case "png":
+		return png.Encode(w, img)
+	case "gif":
+		return gif.Encode(w, img, nil)
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// EncodeBytes 编码图片到内存
+func EncodeBytes(img image.Image, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, img, format, quality); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MimeType 获取格式对应的MIME类型
+func MimeType(format string) string {
+	return formats[format].mimeType
+}
+
+// Extension 获取格式的标准扩展名
+func Extension(format string) string {
+	if f, ok := formats[format]; ok {
+		return f.extensions[0]
+	}
+	return ""
+}
+
+// FormatOf 根据MIME类型或扩展名获取格式，兼容没有记录MIME类型的历史图片。
+// 两者都有时以MIME类型为准
+func FormatOf(mimeType, ext string) string {
+	for format, f := range formats {
+		if f.mimeType == mimeType {
+			return format
+		}
+	}
+	for format, f := range formats {
+		if hasExtension("."+ext, f.extensions) {
+			return format
+		}
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name                string
+		width, height, size int
+		wantW, wantH        int
+	}{
+		{"landscape", 1000, 500, 200, 200, 100},
+		{"portrait", 500, 1000, 200, 100, 200},
+		{"square", 900, 900, 300, 300, 300},
+		{"odd ratio rounds down", 1000, 333, 200, 200, 66},
+		{"thin strip keeps one pixel", 3000, 1, 100, 100, 1},
+		{"tall strip keeps one pixel", 1, 3000, 100, 1, 100},
+		// 不放大
+		{"smaller than target", 100, 50, 200, 100, 50},
+		{"equal to target", 200, 120, 200, 200, 120},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewRGBA(image.Rect(0, 0, tt.width, tt.height))
+			dst := Fit(src, tt.size)
+
+			bounds := dst.Bounds()
+			if bounds.Dx() != tt.wantW || bounds.Dy() != tt.wantH {
+				t.Fatalf("Fit(%dx%d, %d) = %dx%d, want %dx%d",
+					tt.width, tt.height, tt.size, bounds.Dx(), bounds.Dy(), tt.wantW, tt.wantH)
+			}
+			if bounds.Dx() > tt.width || bounds.Dy() > tt.height {
+				t.Fatalf("Fit upscaled %dx%d to %dx%d", tt.width, tt.height, bounds.Dx(), bounds.Dy())
+			}
+			// 宽高比误差不超过取整造成的一个像素
+			if diff := bounds.Dx()*tt.height - bounds.Dy()*tt.width; abs(diff) > max(tt.width, tt.height) {
+				t.Fatalf("Fit changed the aspect ratio: %dx%d -> %dx%d", tt.width, tt.height, bounds.Dx(), bounds.Dy())
+			}
+			if tt.width <= tt.size && tt.height <= tt.size && dst != image.Image(src) {
+				t.Fatalf("Fit copied an image that already fits")
+			}
+		})
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func TestEncode(t *testing.T) {
+	src := testImage(32, 24)
+	for _, format := range []string{"jpeg", "png", "gif"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, src, format, 0); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			decoded, got, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if got != format || decoded.Bounds() != src.Bounds() {
+				t.Fatalf("decoded %s %v, want %s %v", got, decoded.Bounds(), format, src.Bounds())
+			}
+		})
+	}
+
+	if err := Encode(&bytes.Buffer{}, src, "webp", 0); err == nil {
+		t.Fatalf("Encode(webp) succeeded")
+	}
+
+	// 质量为0时使用默认质量
+	defaulted, err := EncodeBytes(src, "jpeg", 0)
+	if err != nil {
+		t.Fatalf("EncodeBytes: %v", err)
+	}
+	explicit, err := EncodeBytes(src, "jpeg", DefaultJPEGQuality)
+	if err != nil {
+		t.Fatalf("EncodeBytes: %v", err)
+	}
+	if !bytes.Equal(defaulted, explicit) {
+		t.Fatalf("quality 0 did not use DefaultJPEGQuality")
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		mimeType string
+		ext      string
+		want     string
+	}{
+		{"mime and extension", "image/png", "png", "png"},
+		{"mime only", "image/jpeg", "", "jpeg"},
+		// 历史图片只记录了扩展名
+		{"legacy jpg", "", "jpg", "jpeg"},
+		{"legacy jpeg", "", "jpeg", "jpeg"},
+		{"legacy upper case", "", "JPG", "jpeg"},
+		{"legacy png", "", "png", "png"},
+		{"legacy gif", "", "gif", "gif"},
+		{"mime wins over extension", "image/png", "jpg", "png"},
+		{"unknown extension", "", "bmp", ""},
+		{"nothing recorded", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 多次执行，避免结果依赖map遍历顺序
+			for i := 0; i < 20; i++ {
+				if got := FormatOf(tt.mimeType, tt.ext); got != tt.want {
+					t.Fatalf("FormatOf(%q, %q) = %q, want %q", tt.mimeType, tt.ext, got, tt.want)
+				}
+			}
+		})
+	}
+
+	if Extension("jpeg") != ".jpg" || Extension("webp") != "" {
+		t.Fatalf("Extension = %q, %q", Extension("jpeg"), Extension("webp"))
+	}
+}
@@ -0,0 +1,117 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// 缩放模式
+const (
+	FitContain = "contain" // 等比缩放到目标尺寸以内
+	FitCover   = "cover"   // 等比缩放铺满目标尺寸，居中裁剪
+)
+
+// ErrInvalidTransform 无效的变换参数
+var ErrInvalidTransform = errors.New("invalid transform parameters")
+
+// TransformOptions 图片变换参数
+type TransformOptions struct {
+	Width   int
+	Height  int
+	Fit     string
+	Format  string
+	Quality int
+}
+
+// Normalize 补全默认值并校验参数，allowedSizes为空时不限制尺寸
+func (o TransformOptions) Normalize(sourceFormat string, allowedSizes []int) (TransformOptions, error) {
+	if o.Width < 0 || o.Height < 0 {
+		return o, ErrInvalidTransform
+	}
+	if !sizeAllowed(o.Width, allowedSizes) || !sizeAllowed(o.Height, allowedSizes) {
+		return o, fmt.Errorf("%w: size not allowed", ErrInvalidTransform)
+	}
+
+	switch o.Fit {
+	case "":
+		o.Fit = FitContain
+	case FitContain, FitCover:
+	default:
+		return o, fmt.Errorf("%w: fit must be cover or contain", ErrInvalidTransform)
+	}
+	// cover需要同时指定宽高
+	if o.Fit == FitCover && (o.Width == 0 || o.Height == 0) {
+		o.Fit = FitContain
+	}
+
+	if o.Format == "" {
+		o.Format = sourceFormat
+	}
+	if _, ok := formats[o.Format]; !ok {
+		return o, fmt.Errorf("%w: format must be png, jpeg or gif", ErrInvalidTransform)
+	}
+
+	// 质量只对JPEG有效，按10取整以限制缓存组合数
+	if o.Format != "jpeg" {
+		o.Quality = 0
+	} else if o.Quality == 0 {
+		o.Quality = DefaultJPEGQuality
+	} else if o.Quality < 1 || o.Quality > 100 {
+		return o, fmt.Errorf("%w: quality must be between 1 and 100", ErrInvalidTransform)
+	} else {
+		o.Quality = max(10, (o.Quality+5)/10*10)
+	}
+
+	return o, nil
+}
+
+// CacheKey 规范化参数生成的缓存键
+func (o TransformOptions) CacheKey() string {
+	return fmt.Sprintf("w%d_h%d_%s_q%d.%s", o.Width, o.Height, o.Fit, o.Quality, o.Format)
+}
+
+// Transform 按参数缩放或裁剪图片
+func Transform(src image.Image, o TransformOptions) image.Image {
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+
+	if o.Fit == FitCover {
+		// 在原图中取与目标宽高比一致的居中区域，细长的原图至少保留一个像素
+		cropWidth, cropHeight := srcWidth, max(1, srcWidth*o.Height/o.Width)
+		if cropHeight > srcHeight {
+			cropWidth, cropHeight = max(1, srcHeight*o.Width/o.Height), srcHeight
+		}
+		x := bounds.Min.X + (srcWidth-cropWidth)/2
+		y := bounds.Min.Y + (srcHeight-cropHeight)/2
+		return scaleRect(src, image.Rect(x, y, x+cropWidth, y+cropHeight), o.Width, o.Height)
+	}
+
+	// contain：不放大
+	width, height := srcWidth, srcHeight
+	if o.Width > 0 && width > o.Width {
+		height = max(1, height*o.Width/width)
+		width = o.Width
+	}
+	if o.Height > 0 && height > o.Height {
+		width = max(1, width*o.Height/height)
+		height = o.Height
+	}
+	if width == srcWidth && height == srcHeight {
+		return src
+	}
+	return scale(src, width, height)
+}
+
+// sizeAllowed 判断尺寸是否在允许列表中，0表示未指定
+func sizeAllowed(size int, allowedSizes []int) bool {
+	if size == 0 || len(allowedSizes) == 0 {
+		return true
+	}
+	for _, allowed := range allowedSizes {
+		if size == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package imaging
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	sizes := []int{100, 200, 400}
+
+	tests := []struct {
+		name    string
+		opts    TransformOptions
+		source  string
+		allowed []int
+		want    TransformOptions
+		invalid bool
+	}{
+		{"defaults", TransformOptions{Width: 100}, "png", sizes,
+			TransformOptions{Width: 100, Fit: FitContain, Format: "png"}, false},
+		{"jpeg default quality", TransformOptions{Width: 100}, "jpeg", sizes,
+			TransformOptions{Width: 100, Fit: FitContain, Format: "jpeg", Quality: DefaultJPEGQuality}, false},
+		{"quality rounded", TransformOptions{Width: 100, Format: "jpeg", Quality: 87}, "png", sizes,
+			TransformOptions{Width: 100, Fit: FitContain, Format: "jpeg", Quality: 90}, false},
+		{"low quality rounded up", TransformOptions{Width: 100, Format: "jpeg", Quality: 1}, "png", sizes,
+			TransformOptions{Width: 100, Fit: FitContain, Format: "jpeg", Quality: 10}, false},
+		{"quality ignored for png", TransformOptions{Width: 100, Format: "png", Quality: 50}, "jpeg", sizes,
+			TransformOptions{Width: 100, Fit: FitContain, Format: "png"}, false},
+		{"cover", TransformOptions{Width: 100, Height: 200, Fit: FitCover}, "gif", sizes,
+			TransformOptions{Width: 100, Height: 200, Fit: FitCover, Format: "gif"}, false},
+		// cover 缺少一边时按 contain 处理
+		{"cover without height", TransformOptions{Width: 100, Fit: FitCover}, "png", sizes,
+			TransformOptions{Width: 100, Fit: FitContain, Format: "png"}, false},
+		{"any size without allow list", TransformOptions{Width: 123}, "png", nil,
+			TransformOptions{Width: 123, Fit: FitContain, Format: "png"}, false},
+
+		{"size not allowed", TransformOptions{Width: 150}, "png", sizes, TransformOptions{}, true},
+		{"height not allowed", TransformOptions{Width: 100, Height: 1}, "png", sizes, TransformOptions{}, true},
+		{"negative width", TransformOptions{Width: -100}, "png", nil, TransformOptions{}, true},
+		{"unknown fit", TransformOptions{Width: 100, Fit: "stretch"}, "png", sizes, TransformOptions{}, true},
+		{"unknown format", TransformOptions{Width: 100, Format: "webp"}, "png", sizes, TransformOptions{}, true},
+		{"unknown source format", TransformOptions{Width: 100}, "", sizes, TransformOptions{}, true},
+		{"quality too high", TransformOptions{Width: 100, Format: "jpeg", Quality: 101}, "png", sizes, TransformOptions{}, true},
+		{"negative quality", TransformOptions{Width: 100, Format: "jpeg", Quality: -1}, "png", sizes, TransformOptions{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.opts.Normalize(tt.source, tt.allowed)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidTransform) {
+					t.Fatalf("Normalize = %+v, %v, want ErrInvalidTransform", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Normalize = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	// 规范化后等价的参数使用同一个缓存键
+	a, _ := TransformOptions{Width: 100, Format: "jpeg", Quality: 86}.Normalize("png", nil)
+	b, _ := TransformOptions{Width: 100, Fit: FitContain, Format: "jpeg", Quality: 94}.Normalize("png", nil)
+	if a.CacheKey() != b.CacheKey() {
+		t.Fatalf("CacheKey %q != %q", a.CacheKey(), b.CacheKey())
+	}
+	if a.CacheKey() != "w100_h0_contain_q90.jpeg" {
+		t.Fatalf("CacheKey = %q", a.CacheKey())
+	}
+
+	c, _ := TransformOptions{Width: 100, Height: 100, Fit: FitCover}.Normalize("png", nil)
+	if c.CacheKey() == a.CacheKey() {
+		t.Fatalf("different transforms share cache key %q", c.CacheKey())
+	}
+}
+
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		opts          TransformOptions
+		wantW, wantH  int
+	}{
+		{"contain by width", 400, 200, TransformOptions{Width: 100, Fit: FitContain}, 100, 50},
+		{"contain by height", 400, 200, TransformOptions{Height: 100, Fit: FitContain}, 200, 100},
+		{"contain in box", 400, 200, TransformOptions{Width: 100, Height: 100, Fit: FitContain}, 100, 50},
+		{"contain never upscales", 40, 20, TransformOptions{Width: 100, Height: 100, Fit: FitContain}, 40, 20},
+		{"cover crops landscape", 400, 200, TransformOptions{Width: 100, Height: 100, Fit: FitCover}, 100, 100},
+		{"cover crops portrait", 200, 400, TransformOptions{Width: 200, Height: 100, Fit: FitCover}, 200, 100},
+		{"cover fills small source", 40, 20, TransformOptions{Width: 100, Height: 100, Fit: FitCover}, 100, 100},
+		// 细长的原图裁剪区域至少一个像素，不会输出空图
+		{"cover from horizontal strip", 1000, 1, TransformOptions{Width: 100, Height: 200, Fit: FitCover}, 100, 200},
+		{"cover from vertical strip", 1, 1000, TransformOptions{Width: 200, Height: 100, Fit: FitCover}, 200, 100},
+		{"contain thin strip", 1000, 2, TransformOptions{Width: 100, Fit: FitContain}, 100, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewRGBA(image.Rect(0, 0, tt.width, tt.height))
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					src.Set(x, y, color.RGBA{R: 200, A: 255})
+				}
+			}
+
+			dst := Transform(src, tt.opts)
+			bounds := dst.Bounds()
+			if bounds.Dx() != tt.wantW || bounds.Dy() != tt.wantH {
+				t.Fatalf("Transform = %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), tt.wantW, tt.wantH)
+			}
+			// 中心像素来自原图，而不是空白
+			center := bounds.Min.Add(image.Pt(bounds.Dx()/2, bounds.Dy()/2))
+			if _, _, _, a := dst.At(center.X, center.Y).RGBA(); a == 0 {
+				t.Fatalf("Transform produced a transparent image")
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 处理器自身使用的Redis键，与具体任务类型无关。
+// 任务队列和处理中列表由各任务定义的 Queue 决定
+const (
+	RetryQueue      = "task:retry"   // 等待重试的任务，按到期时间排序
+	DeadLetterQueue = "task:dead"    // 重试耗尽的任务
+	WorkersKey      = "task:workers" // 处理进程ID集合
+	WorkerLeaseKey  = "task:lease:"  // 处理进程租约前缀，按可见性超时续期
+)
+
+// 旧版本使用的键名，启动时迁移到新键名
+const (
+	legacyRetryQueue      = "image:task:retry"
+	legacyDeadLetterQueue = "image:task:dead"
+	legacyWorkersKey      = "image:task:workers"
+	legacyWorkerLeaseKey  = "image:task:lease:"
+)
+
+// migrateKeysScript 将旧键的内容移动到新键，KEYS 按旧键、新键成对传入。
+// 新键不存在时直接重命名（保留TTL），否则按类型合并后删除旧键
+var migrateKeysScript = redis.NewScript(`
+local moved = 0
+for i = 1, #KEYS, 2 do
+	local old, new = KEYS[i], KEYS[i + 1]
+	local kind = redis.call('TYPE', old)['ok']
+	if kind ~= 'none' then
+		if redis.call('EXISTS', new) == 0 then
+			redis.call('RENAME', old, new)
+		else
+			if kind == 'zset' then
+				redis.call('ZUNIONSTORE', new, 2, new, old, 'AGGREGATE', 'MIN')
+			elseif kind == 'set' then
+				redis.call('SUNIONSTORE', new, new, old)
+			elseif kind == 'list' then
+				for _, item in ipairs(redis.call('LRANGE', old, 0, -1)) do
+					redis.call('RPUSH', new, item)
+				end
+			end
+			redis.call('DEL', old)
+		end
+		moved = moved + 1
+	end
+end
+return moved
+`)
+
+// MigrateLegacyKeys 将旧版本以图片命名的重试队列、死信队列和进程登记迁移到新键名。
+// 旧键不存在时不做任何操作，可重复执行
+func MigrateLegacyKeys(ctx context.Context, redisClient *redis.Client) (int, error) {
+	workers, err := redisClient.SMembers(ctx, legacyWorkersKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list legacy task workers: %v", err)
+	}
+
+	keys := []string{
+		legacyRetryQueue, RetryQueue,
+		legacyDeadLetterQueue, DeadLetterQueue,
+		legacyWorkersKey, WorkersKey,
+	}
+	for _, workerID := range workers {
+		keys = append(keys, legacyWorkerLeaseKey+workerID, WorkerLeaseKey+workerID)
+	}
+
+	moved, err := migrateKeysScript.Run(ctx, redisClient, keys).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to migrate legacy task keys: %v", err)
+	}
+	return moved, nil
+}
@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-admin/testutil"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMigrateLegacyKeys(t *testing.T) {
+	_, client := testutil.NewRedis(t)
+	ctx := context.Background()
+
+	client.ZAdd(ctx, legacyRetryQueue, redis.Z{Score: 1, Member: "old-retry"})
+	client.ZAdd(ctx, RetryQueue, redis.Z{Score: 2, Member: "new-retry"})
+	client.RPush(ctx, legacyDeadLetterQueue, "dead-1", "dead-2")
+	client.SAdd(ctx, legacyWorkersKey, "worker-1")
+	client.Set(ctx, legacyWorkerLeaseKey+"worker-1", 1, time.Minute)
+
+	moved, err := MigrateLegacyKeys(ctx, client)
+	if err != nil {
+		t.Fatalf("MigrateLegacyKeys: %v", err)
+	}
+	if moved != 4 {
+		t.Errorf("moved %d keys, want 4", moved)
+	}
+
+	// 新键已存在时合并
+	if retry := client.ZRange(ctx, RetryQueue, 0, -1).Val(); len(retry) != 2 {
+		t.Errorf("retry queue = %v, want both members", retry)
+	}
+	if dead := client.LRange(ctx, DeadLetterQueue, 0, -1).Val(); len(dead) != 2 || dead[0] != "dead-1" {
+		t.Errorf("dead letter queue = %v, want [dead-1 dead-2]", dead)
+	}
+	if !client.SIsMember(ctx, WorkersKey, "worker-1").Val() {
+		t.Error("worker was not migrated")
+	}
+	if ttl := client.TTL(ctx, WorkerLeaseKey+"worker-1").Val(); ttl <= 0 {
+		t.Errorf("lease TTL = %v, want the original TTL", ttl)
+	}
+	for _, key := range []string{legacyRetryQueue, legacyDeadLetterQueue, legacyWorkersKey, legacyWorkerLeaseKey + "worker-1"} {
+		if client.Exists(ctx, key).Val() != 0 {
+			t.Errorf("legacy key %s still exists", key)
+		}
+	}
+
+	// 可重复执行
+	if moved, err := MigrateLegacyKeys(ctx, client); err != nil || moved != 0 {
+		t.Fatalf("second MigrateLegacyKeys = %d, %v; want 0, nil", moved, err)
+	}
+}
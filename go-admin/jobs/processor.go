@@ -0,0 +1,553 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-admin/config"
+	"go-admin/events"
+	"go-admin/models"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Processor 后台任务处理器。
+// 任务通过BLMOVE原子地转移到本进程的处理中列表，处理完成后才移除，
+// 进程崩溃时由其他进程在租约过期后重新入队，因此任务至少被处理一次
+type Processor struct {
+	redisClient *redis.Client
+	registry    *Registry
+	workerID    string
+	taskConfig  config.TaskConfig // 启动时的配置，重试策略从 settings 读取以支持热加载
+	settings    *config.Store
+	metrics     map[string]*queueMetrics
+	ctx         context.Context // 取消后停止获取新任务
+	cancel      context.CancelFunc
+	taskCtx     context.Context // 取消后中断正在处理的任务
+	taskCancel  context.CancelFunc
+	wg          sync.WaitGroup
+	leaseDone   chan struct{}
+	random      func() float64 // 重试抖动使用的[0,1)随机数
+}
+
+// queueMetrics 单个队列在本进程内的处理计数
+type queueMetrics struct {
+	inFlight  atomic.Int64
+	completed atomic.Int64
+	failed    atomic.Int64
+}
+
+// NewProcessor 创建后台任务处理器，任务类型需在启动前注册
+func NewProcessor(redisClient *redis.Client, registry *Registry, settings *config.Store) *Processor {
+	ctx, cancel := context.WithCancel(context.Background())
+	taskCtx, taskCancel := context.WithCancel(context.Background())
+	hostname, _ := os.Hostname()
+
+	return &Processor{
+		redisClient: redisClient,
+		registry:    registry,
+		workerID:    fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.New().String()[:8]),
+		taskConfig:  settings.Get().Task,
+		settings:    settings,
+		metrics:     make(map[string]*queueMetrics),
+		ctx:         ctx,
+		cancel:      cancel,
+		taskCtx:     taskCtx,
+		taskCancel:  taskCancel,
+		leaseDone:   make(chan struct{}),
+		random:      rand.Float64,
+	}
+}
+
+// Registry 获取任务类型注册表
+func (p *Processor) Registry() *Registry {
+	return p.registry
+}
+
+// Start 启动任务处理器
+func (p *Processor) Start() {
+	queues := p.registry.Queues()
+	for _, queue := range queues {
+		p.metrics[queue] = &queueMetrics{}
+	}
+
+	// 迁移旧版本的键名
+	if moved, err := MigrateLegacyKeys(p.taskCtx, p.redisClient); err != nil {
+		log.Printf("Failed to migrate legacy task keys: %v", err)
+	} else if moved > 0 {
+		log.Printf("Migrated %d legacy task keys", moved)
+	}
+
+	// 登记处理进程并获取租约
+	if err := p.renewLease(); err != nil {
+		log.Printf("Failed to acquire task worker lease: %v", err)
+	}
+	p.redisClient.SAdd(p.taskCtx, WorkersKey, p.workerID)
+
+	// 续期租约，直到正在处理的任务全部结束
+	go p.keepLease()
+
+	// 每个队列启动多个处理协程
+	workers := max(p.taskConfig.Workers, 1)
+	for _, queue := range queues {
+		for i := 0; i < workers; i++ {
+			p.wg.Add(1)
+			go p.processQueue(queue)
+		}
+	}
+
+	// 回收失联进程的任务
+	p.wg.Add(1)
+	go p.every(p.taskConfig.VisibilityTimeout, p.reapOnce)
+
+	// 将到期的重试任务放回队列
+	p.wg.Add(1)
+	go p.every(1*time.Second, p.promoteDueJobs)
+
+	// 清理过期的任务历史记录
+	p.wg.Add(1)
+	go p.every(1*time.Hour, func(ctx context.Context) {
+		pruneRecords(p.taskConfig.HistoryRetention)
+	})
+
+	// 注册的周期任务
+	for _, periodic := range p.registry.Periodic() {
+		p.wg.Add(1)
+		go p.every(periodic.Interval, periodic.Run)
+	}
+
+	log.Printf("Job processor started (worker %s, %d workers per queue)", p.workerID, workers)
+}
+
+// Stop 停止任务处理器：不再获取新任务，等待正在处理的任务完成，
+// 超过 TaskConfig.ShutdownTimeout 后中断剩余任务，未确认的任务归还到队列
+func (p *Processor) Stop() {
+	p.cancel()
+
+	drained := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(p.taskConfig.ShutdownTimeout):
+		log.Printf("Timed out waiting for in-flight jobs, aborting")
+		p.taskCancel()
+		<-drained
+	}
+	p.taskCancel()
+	<-p.leaseDone
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	requeued, err := p.requeueWorker(ctx, p.workerID)
+	if err != nil {
+		log.Printf("Failed to requeue unfinished jobs: %v", err)
+		// 保留租约记录，由其他进程在租约过期后回收
+		log.Println("Job processor stopped")
+		return
+	}
+	p.redisClient.Del(ctx, WorkerLeaseKey+p.workerID)
+	p.redisClient.SRem(ctx, WorkersKey, p.workerID)
+	log.Printf("Job processor stopped, %d unfinished jobs requeued", requeued)
+}
+
+// Metrics 获取本进程各队列的处理计数
+func (p *Processor) Metrics() []models.QueueMetrics {
+	queues := p.registry.Queues()
+	result := make([]models.QueueMetrics, 0, len(queues))
+	for _, queue := range queues {
+		metrics := p.metrics[queue]
+		if metrics == nil {
+			continue
+		}
+		result = append(result, models.QueueMetrics{
+			Queue:     queue,
+			Workers:   max(p.taskConfig.Workers, 1),
+			InFlight:  metrics.inFlight.Load(),
+			Completed: metrics.completed.Load(),
+			Failed:    metrics.failed.Load(),
+		})
+	}
+	return result
+}
+
+// every 按固定周期执行，停止获取新任务时退出
+func (p *Processor) every(interval time.Duration, run func(ctx context.Context)) {
+	defer p.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			run(p.taskCtx)
+		}
+	}
+}
+
+// processQueue 消费队列中的任务
+func (p *Processor) processQueue(queue string) {
+	defer p.wg.Done()
+	processing := config.ProcessingQueueKey(queue, p.workerID)
+	metrics := p.metrics[queue]
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		default:
+			// 从队列中获取任务，同时放入处理中列表
+			payload, err := p.redisClient.BLMove(p.ctx, queue, processing, "LEFT", "LEFT", 1*time.Second).Result()
+			if err != nil {
+				if err != redis.Nil && p.ctx.Err() == nil {
+					log.Printf("Error getting job from %s: %v", queue, err)
+				}
+				continue
+			}
+
+			// 解析任务
+			var job models.Job
+			if err := job.FromJSON([]byte(payload)); err != nil {
+				log.Printf("Error unmarshaling job from %s: %v", queue, err)
+				p.ack(processing, payload)
+				continue
+			}
+
+			// 重复投递的已完成任务和已取消的任务直接确认
+			if status, claimed := p.claim(&job); !claimed {
+				log.Printf("Job %s already %s, skipping", job.ID, status)
+				p.ack(processing, payload)
+				continue
+			}
+
+			// 处理任务
+			metrics.inFlight.Add(1)
+			p.run(&job)
+			metrics.inFlight.Add(-1)
+
+			// 停止过程中被中断的任务保留在处理中列表，停止时归还
+			if p.taskCtx.Err() == nil {
+				p.ack(processing, payload)
+			}
+		}
+	}
+}
+
+// run 执行任务并处理结果
+func (p *Processor) run(job *models.Job) {
+	definition, ok := p.registry.Lookup(job.Type)
+	if !ok {
+		log.Printf("No handler registered for job %s of type %s", job.ID, job.Type)
+		p.deadLetter(job, fmt.Sprintf("%v: %s", ErrUnknownJobType, job.Type))
+		p.updateStatus(job)
+		return
+	}
+
+	log.Printf("Processing %s job: %s", job.Type, job.ID)
+
+	message, err := definition.Handler(p.taskCtx, job)
+	if err != nil {
+		log.Printf("%s job %s failed: %v", job.Type, job.ID, err)
+		p.handleFailure(&definition, job, err.Error())
+		return
+	}
+
+	p.handleSuccess(&definition, job, message)
+	log.Printf("%s job completed: %s", job.Type, job.ID)
+}
+
+// ack 确认任务，将其从处理中列表移除
+func (p *Processor) ack(processing, payload string) {
+	if err := p.redisClient.LRem(p.taskCtx, processing, 1, payload).Err(); err != nil {
+		log.Printf("Failed to ack job in %s: %v", processing, err)
+	}
+}
+
+// claimScript 任务未完成且未被取消时原子地将状态更新为处理中，否则返回当前状态
+var claimScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current then
+	local ok, job = pcall(cjson.decode, current)
+	if ok and (job.status == ARGV[3] or job.status == ARGV[4]) then
+		return job.status
+	end
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return ''
+`)
+
+// claim 将任务标记为处理中，任务已经成功处理过或已被取消时返回false和当前状态
+func (p *Processor) claim(job *models.Job) (string, bool) {
+	job.Status = config.TaskStatusProcessing
+	jobJSON, _ := json.Marshal(job)
+	status, err := claimScript.Run(p.taskCtx, p.redisClient, []string{StatusKey(job.ID)},
+		jobJSON, statusTTL.Milliseconds(), config.TaskStatusCompleted, config.TaskStatusCancelled).Text()
+	if err != nil {
+		// 无法确认状态时照常处理，处理逻辑是幂等的
+		log.Printf("Failed to claim job %s: %v", job.ID, err)
+		p.updateStatus(job)
+		return "", true
+	}
+	if status != "" {
+		return status, false
+	}
+	SaveRecord(job)
+	p.notify(job)
+	return "", true
+}
+
+// handleSuccess 处理任务成功
+func (p *Processor) handleSuccess(definition *Definition, job *models.Job, message string) {
+	job.Status = config.TaskStatusCompleted
+	job.NextAttemptAt = nil
+	p.metrics[definition.Queue].completed.Add(1)
+
+	p.publish(definition, job, true, message, time.Now())
+
+	// 更新任务状态
+	p.updateStatus(job)
+}
+
+// handleFailure 处理任务失败
+func (p *Processor) handleFailure(definition *Definition, job *models.Job, message string) {
+	job.RetryCount++
+	job.LastError = message
+	p.metrics[definition.Queue].failed.Add(1)
+
+	// 重试次数耗尽，标记为失败并转入死信队列
+	taskConfig := p.settings.Get().Task
+	if job.RetryCount > taskConfig.MaxRetries {
+		failedAt := p.deadLetter(job, message)
+		p.publish(definition, job, false, message, failedAt)
+	} else {
+		// 按退避时间延迟重试
+		nextAttemptAt := time.Now().Add(retryDelay(&taskConfig, job.RetryCount, p.random))
+		job.Status = config.TaskStatusPending
+		job.NextAttemptAt = &nextAttemptAt
+		jobJSON, _ := json.Marshal(job)
+		if err := p.redisClient.ZAdd(p.taskCtx, RetryQueue, redis.Z{
+			Score:  float64(nextAttemptAt.UnixMilli()),
+			Member: jobJSON,
+		}).Err(); err != nil {
+			log.Printf("Failed to schedule retry for job %s: %v", job.ID, err)
+		}
+	}
+
+	// 更新任务状态
+	p.updateStatus(job)
+}
+
+// deadLetter 标记任务失败并转入死信队列
+func (p *Processor) deadLetter(job *models.Job, message string) time.Time {
+	failedAt := time.Now()
+	job.Status = config.TaskStatusFailed
+	job.LastError = message
+	job.FailedAt = &failedAt
+	job.NextAttemptAt = nil
+
+	jobJSON, _ := json.Marshal(job)
+	if err := p.redisClient.LPush(p.taskCtx, DeadLetterQueue, jobJSON).Err(); err != nil {
+		log.Printf("Failed to dead-letter job %s: %v", job.ID, err)
+	}
+	return failedAt
+}
+
+// publish 发布任务结果
+func (p *Processor) publish(definition *Definition, job *models.Job, success bool, message string, completedAt time.Time) {
+	if definition.Channel == "" {
+		return
+	}
+	result := models.JobResult{
+		TaskID:      job.ID,
+		Type:        job.Type,
+		Success:     success,
+		Message:     message,
+		CompletedAt: completedAt,
+	}
+	resultJSON, _ := json.Marshal(result)
+	p.redisClient.Publish(p.taskCtx, definition.Channel, resultJSON)
+}
+
+// updateStatus 更新任务状态和历史记录，并推送任务进度事件
+func (p *Processor) updateStatus(job *models.Job) {
+	jobJSON, _ := json.Marshal(job)
+	p.redisClient.Set(p.taskCtx, StatusKey(job.ID), jobJSON, statusTTL)
+	SaveRecord(job)
+	p.notify(job)
+}
+
+// notify 推送任务进度事件
+func (p *Processor) notify(job *models.Job) {
+	event := &models.Event{
+		UserID:   job.OwnerID,
+		TaskID:   job.ID,
+		TaskType: job.Type,
+	}
+	switch job.Status {
+	case config.TaskStatusProcessing:
+		event.Type = models.EventTaskProcessing
+	case config.TaskStatusCompleted:
+		event.Type = models.EventTaskCompleted
+	case config.TaskStatusFailed:
+		event.Type = models.EventTaskFailed
+		event.Message = job.LastError
+	case config.TaskStatusPending:
+		if job.NextAttemptAt == nil {
+			return
+		}
+		event.Type = models.EventTaskRetrying
+		event.Message = job.LastError
+	default:
+		return
+	}
+	events.Publish(p.taskCtx, p.redisClient, event)
+}
+
+// renewLease 续期本进程的租约
+func (p *Processor) renewLease() error {
+	return p.redisClient.Set(p.taskCtx, WorkerLeaseKey+p.workerID, time.Now().Unix(), p.taskConfig.VisibilityTimeout).Err()
+}
+
+// keepLease 按可见性超时的三分之一周期续期租约
+func (p *Processor) keepLease() {
+	defer close(p.leaseDone)
+	ticker := time.NewTicker(p.taskConfig.VisibilityTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.taskCtx.Done():
+			return
+		case <-ticker.C:
+			if err := p.renewLease(); err != nil && p.taskCtx.Err() == nil {
+				log.Printf("Failed to renew task worker lease: %v", err)
+			}
+		}
+	}
+}
+
+// reapOnce 将租约过期的进程未完成的任务重新入队
+func (p *Processor) reapOnce(ctx context.Context) {
+	workers, err := p.redisClient.SMembers(ctx, WorkersKey).Result()
+	if err != nil {
+		log.Printf("Failed to list task workers: %v", err)
+		return
+	}
+
+	for _, workerID := range workers {
+		if workerID == p.workerID {
+			continue
+		}
+		alive, err := p.redisClient.Exists(ctx, WorkerLeaseKey+workerID).Result()
+		if err != nil || alive > 0 {
+			continue
+		}
+
+		requeued, err := p.requeueWorker(ctx, workerID)
+		if err != nil {
+			log.Printf("Failed to requeue jobs of worker %s: %v", workerID, err)
+			continue
+		}
+		p.redisClient.SRem(ctx, WorkersKey, workerID)
+		log.Printf("Reaped worker %s, %d jobs requeued", workerID, requeued)
+	}
+}
+
+// requeueWorker 将处理进程未确认的任务逐个原子地移回队列头部
+func (p *Processor) requeueWorker(ctx context.Context, workerID string) (int, error) {
+	requeued := 0
+	for _, queue := range p.registry.Queues() {
+		processing := config.ProcessingQueueKey(queue, workerID)
+		for {
+			err := p.redisClient.LMove(ctx, processing, queue, "LEFT", "LEFT").Err()
+			if err == redis.Nil {
+				break
+			}
+			if err != nil {
+				return requeued, err
+			}
+			requeued++
+		}
+	}
+	return requeued, nil
+}
+
+// promoteScript 原子地将到期任务从重试集合移到工作队列，已被其他进程移走时返回0
+var promoteScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('RPUSH', KEYS[2], ARGV[1])
+return 1
+`)
+
+// promoteDueJobs 将到期的重试任务放回对应的工作队列
+func (p *Processor) promoteDueJobs(ctx context.Context) {
+	due, err := p.redisClient.ZRangeByScore(ctx, RetryQueue, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Count: 100,
+	}).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to get due retry jobs: %v", err)
+		}
+		return
+	}
+
+	for _, payload := range due {
+		var job models.Job
+		if err := job.FromJSON([]byte(payload)); err != nil {
+			log.Printf("Error unmarshaling retry job: %v", err)
+			p.redisClient.ZRem(ctx, RetryQueue, payload)
+			continue
+		}
+
+		definition, ok := p.registry.Lookup(job.Type)
+		if !ok {
+			// 没有对应的队列，直接转入死信队列
+			if removed, err := p.redisClient.ZRem(ctx, RetryQueue, payload).Result(); err == nil && removed == 1 {
+				p.deadLetter(&job, fmt.Sprintf("%v: %s", ErrUnknownJobType, job.Type))
+				p.updateStatus(&job)
+			}
+			continue
+		}
+		if err := promoteScript.Run(ctx, p.redisClient, []string{RetryQueue, definition.Queue}, payload).Err(); err != nil {
+			log.Printf("Failed to promote retry job %s: %v", job.ID, err)
+		}
+	}
+}
+
+// retryDelay 第attempt次重试的延迟：基础延迟按指数增长，加入随机抖动，结果不超过上限。
+// random 返回[0,1)内的随机数
+func retryDelay(taskConfig *config.TaskConfig, attempt int, random func() float64) time.Duration {
+	delay := taskConfig.RetryBaseDelay
+	for i := 1; i < attempt && delay < taskConfig.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, taskConfig.RetryMaxDelay)
+
+	if jitter := taskConfig.RetryJitter; jitter > 0 {
+		if jitter > 1 {
+			jitter = 1
+		}
+		// 在 [delay*(1-jitter), delay*(1+jitter)] 内随机
+		delay = time.Duration(float64(delay) * (1 - jitter + 2*jitter*random()))
+	}
+	return min(delay, taskConfig.RetryMaxDelay)
+}
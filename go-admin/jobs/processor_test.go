@@ -0,0 +1,363 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-admin/config"
+	"go-admin/database"
+	"go-admin/models"
+	"go-admin/testutil"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const testQueue = "test:queue"
+
+// newTestProcessor 创建使用临时数据库和内存Redis的处理器，handler 处理 test 类型的任务
+func newTestProcessor(t *testing.T, handler Handler) (*Processor, *redis.Client) {
+	t.Helper()
+	cfg := testutil.Config(t)
+	cfg.Task.Workers = 1
+	cfg.Task.MaxRetries = 1
+	cfg.Task.RetryBaseDelay = time.Millisecond
+	cfg.Task.RetryJitter = 0
+	cfg.Task.ShutdownTimeout = time.Second
+	testutil.NewDB(t, cfg)
+	_, client := testutil.NewRedis(t)
+
+	registry := NewRegistry()
+	registry.Register(Definition{Type: "test", Queue: testQueue, Handler: handler})
+	p := NewProcessor(client, registry, config.NewStore(cfg, nil))
+	p.metrics[testQueue] = &queueMetrics{}
+	return p, client
+}
+
+// newTestJob 创建 test 类型的任务
+func newTestJob(t *testing.T) *models.Job {
+	t.Helper()
+	job, err := models.NewJob("test", map[string]int{"n": 1})
+	if err != nil {
+		t.Fatalf("NewJob: %v", err)
+	}
+	return job
+}
+
+// loadStatus 读取Redis中的任务状态
+func loadStatus(t *testing.T, client *redis.Client, id string) *models.Job {
+	t.Helper()
+	data, err := client.Get(context.Background(), StatusKey(id)).Bytes()
+	if err != nil {
+		t.Fatalf("get status of %s: %v", id, err)
+	}
+	var job models.Job
+	if err := job.FromJSON(data); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	return &job
+}
+
+func TestProcessorCompletesAndAcksJob(t *testing.T) {
+	var calls atomic.Int32
+	p, client := newTestProcessor(t, func(ctx context.Context, job *models.Job) (string, error) {
+		calls.Add(1)
+		return "done", nil
+	})
+	ctx := context.Background()
+
+	job := newTestJob(t)
+	if err := Enqueue(ctx, client, p.registry, job); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	p.Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for loadStatus(t, client, job.ID).Status != config.TaskStatusCompleted {
+		if time.Now().After(deadline) {
+			t.Fatal("job was not completed in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	p.Stop()
+
+	if calls.Load() != 1 {
+		t.Errorf("handler called %d times, want 1", calls.Load())
+	}
+	processing := config.ProcessingQueueKey(testQueue, p.workerID)
+	if n := client.LLen(ctx, processing).Val(); n != 0 {
+		t.Errorf("processing list has %d jobs after ack, want 0", n)
+	}
+	if n := client.LLen(ctx, testQueue).Val(); n != 0 {
+		t.Errorf("queue has %d jobs, want 0", n)
+	}
+	if client.SIsMember(ctx, WorkersKey, p.workerID).Val() {
+		t.Error("worker is still registered after Stop")
+	}
+
+	var record models.TaskRecord
+	if err := database.DB.First(&record, "id = ?", job.ID).Error; err != nil {
+		t.Fatalf("load record: %v", err)
+	}
+	if record.Status != config.TaskStatusCompleted {
+		t.Errorf("record status = %s, want %s", record.Status, config.TaskStatusCompleted)
+	}
+}
+
+func TestClaimSkipsFinishedJobs(t *testing.T) {
+	p, client := newTestProcessor(t, nil)
+	ctx := context.Background()
+
+	for _, status := range []string{config.TaskStatusCompleted, config.TaskStatusCancelled} {
+		job := newTestJob(t)
+		job.Status = status
+		data, _ := json.Marshal(job)
+		client.Set(ctx, StatusKey(job.ID), data, time.Hour)
+
+		if current, claimed := p.claim(job); claimed || current != status {
+			t.Errorf("claim(%s) = %q, %v; want %q, false", status, current, claimed, status)
+		}
+	}
+
+	job := newTestJob(t)
+	if _, claimed := p.claim(job); !claimed {
+		t.Fatal("claim of a pending job failed")
+	}
+	if status := loadStatus(t, client, job.ID).Status; status != config.TaskStatusProcessing {
+		t.Errorf("status after claim = %s, want %s", status, config.TaskStatusProcessing)
+	}
+}
+
+func TestFailedJobIsRetriedThenDeadLettered(t *testing.T) {
+	p, client := newTestProcessor(t, func(ctx context.Context, job *models.Job) (string, error) {
+		return "", errors.New("boom")
+	})
+	ctx := context.Background()
+	job := newTestJob(t)
+
+	// 第一次失败进入重试集合
+	p.run(job)
+	if status := loadStatus(t, client, job.ID); status.Status != config.TaskStatusPending || status.NextAttemptAt == nil {
+		t.Fatalf("status after first failure = %s (next attempt %v), want pending with retry time", status.Status, status.NextAttemptAt)
+	}
+	if n := client.ZCard(ctx, RetryQueue).Val(); n != 1 {
+		t.Fatalf("retry queue has %d jobs, want 1", n)
+	}
+
+	// 到期后放回工作队列
+	time.Sleep(5 * time.Millisecond)
+	p.promoteDueJobs(ctx)
+	if n := client.ZCard(ctx, RetryQueue).Val(); n != 0 {
+		t.Fatalf("retry queue has %d jobs after promotion, want 0", n)
+	}
+	payload, err := client.LPop(ctx, testQueue).Result()
+	if err != nil {
+		t.Fatalf("promoted job not in queue: %v", err)
+	}
+	var retried models.Job
+	if err := retried.FromJSON([]byte(payload)); err != nil {
+		t.Fatalf("decode promoted job: %v", err)
+	}
+
+	// 重试次数耗尽后进入死信队列
+	p.run(&retried)
+	if status := loadStatus(t, client, job.ID).Status; status != config.TaskStatusFailed {
+		t.Fatalf("status after retries = %s, want %s", status, config.TaskStatusFailed)
+	}
+	if n := client.LLen(ctx, DeadLetterQueue).Val(); n != 1 {
+		t.Fatalf("dead letter queue has %d jobs, want 1", n)
+	}
+	if failed := p.metrics[testQueue].failed.Load(); failed != 2 {
+		t.Errorf("failed count = %d, want 2", failed)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	taskConfig := &config.TaskConfig{
+		RetryBaseDelay: time.Second,
+		RetryMaxDelay:  30 * time.Second,
+	}
+	fixed := func(v float64) func() float64 {
+		return func() float64 { return v }
+	}
+
+	tests := []struct {
+		name    string
+		attempt int
+		jitter  float64
+		random  float64
+		want    time.Duration
+	}{
+		// 无抖动时每次翻倍直到上限
+		{"first attempt", 1, 0, 0.5, time.Second},
+		{"second attempt", 2, 0, 0.5, 2 * time.Second},
+		{"fifth attempt", 5, 0, 0.5, 16 * time.Second},
+		{"capped", 6, 0, 0.5, 30 * time.Second},
+		{"far beyond cap", 60, 0, 0.5, 30 * time.Second},
+		// 抖动范围为 [delay*(1-jitter), delay*(1+jitter)]
+		{"jitter low end", 3, 0.25, 0, 3 * time.Second},
+		{"jitter middle", 3, 0.25, 0.5, 4 * time.Second},
+		{"jitter high end", 3, 0.25, 0.999999, 5 * time.Second},
+		{"jitter above 1 is clamped", 2, 3, 0, 0},
+		// 抖动后仍不超过上限
+		{"jitter capped", 6, 0.5, 0.999999, 30 * time.Second},
+		{"jitter below cap", 6, 0.5, 0, 15 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := *taskConfig
+			cfg.RetryJitter = tt.jitter
+			got := retryDelay(&cfg, tt.attempt, fixed(tt.random))
+			if diff := got - tt.want; diff < -time.Millisecond || diff > time.Millisecond {
+				t.Fatalf("retryDelay(attempt %d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+
+	// 延迟随重试次数单调增长且不超过上限
+	cfg := *taskConfig
+	cfg.RetryJitter = 0.2
+	previous := time.Duration(0)
+	for attempt := 1; attempt <= 10; attempt++ {
+		delay := retryDelay(&cfg, attempt, fixed(0.5))
+		if delay < previous || delay > cfg.RetryMaxDelay {
+			t.Fatalf("retryDelay(attempt %d) = %v after %v, cap %v", attempt, delay, previous, cfg.RetryMaxDelay)
+		}
+		previous = delay
+	}
+}
+
+func TestRetryUsesProcessorRandom(t *testing.T) {
+	p, client := newTestProcessor(t, func(ctx context.Context, job *models.Job) (string, error) {
+		return "", errors.New("boom")
+	})
+	cfg := *p.settings.Get()
+	cfg.Task.RetryBaseDelay = time.Minute
+	cfg.Task.RetryMaxDelay = time.Hour
+	cfg.Task.RetryJitter = 0.5
+	p.settings = config.NewStore(&cfg, nil)
+	p.random = func() float64 { return 0 }
+
+	before := time.Now()
+	job := newTestJob(t)
+	p.run(job)
+
+	// random 为0时延迟为基础延迟的一半
+	scores := client.ZRangeWithScores(context.Background(), RetryQueue, 0, -1).Val()
+	if len(scores) != 1 {
+		t.Fatalf("retry queue = %v, want one job", scores)
+	}
+	delay := time.UnixMilli(int64(scores[0].Score)).Sub(before)
+	if delay < 29*time.Second || delay > 31*time.Second {
+		t.Fatalf("retry scheduled after %v, want 30s", delay)
+	}
+}
+
+func TestPromoteDueJobs(t *testing.T) {
+	p, client := newTestProcessor(t, nil)
+	ctx := context.Background()
+
+	schedule := func(job *models.Job, at time.Time) {
+		data, _ := json.Marshal(job)
+		client.ZAdd(ctx, RetryQueue, redis.Z{Score: float64(at.UnixMilli()), Member: data})
+	}
+	due := newTestJob(t)
+	schedule(due, time.Now().Add(-time.Second))
+	later := newTestJob(t)
+	schedule(later, time.Now().Add(time.Hour))
+	unknown := newTestJob(t)
+	unknown.Type = "unknown"
+	schedule(unknown, time.Now().Add(-time.Second))
+
+	p.promoteDueJobs(ctx)
+	// 重复执行不会重复入队
+	p.promoteDueJobs(ctx)
+
+	queued := client.LRange(ctx, testQueue, 0, -1).Val()
+	if len(queued) != 1 {
+		t.Fatalf("queue = %v, want only the due job", queued)
+	}
+	var promoted models.Job
+	if err := promoted.FromJSON([]byte(queued[0])); err != nil || promoted.ID != due.ID {
+		t.Fatalf("promoted = %+v, %v; want %s", promoted, err, due.ID)
+	}
+
+	remaining := client.ZRange(ctx, RetryQueue, 0, -1).Val()
+	if len(remaining) != 1 {
+		t.Fatalf("retry queue = %v, want only the job that is not due", remaining)
+	}
+	var waiting models.Job
+	if err := waiting.FromJSON([]byte(remaining[0])); err != nil || waiting.ID != later.ID {
+		t.Fatalf("waiting = %+v, %v; want %s", waiting, err, later.ID)
+	}
+	// 没有对应队列的任务转入死信队列
+	if n := client.LLen(ctx, DeadLetterQueue).Val(); n != 1 {
+		t.Fatalf("dead letter queue has %d jobs, want 1", n)
+	}
+}
+
+func TestReapRequeuesJobsOfExpiredWorkers(t *testing.T) {
+	p, client := newTestProcessor(t, nil)
+	ctx := context.Background()
+
+	// 失联的进程：已登记但租约已过期，处理中列表有未确认的任务
+	client.SAdd(ctx, WorkersKey, "dead-worker", "live-worker")
+	client.RPush(ctx, config.ProcessingQueueKey(testQueue, "dead-worker"), "job-1", "job-2")
+	client.RPush(ctx, config.ProcessingQueueKey(testQueue, "live-worker"), "job-3")
+	client.Set(ctx, WorkerLeaseKey+"live-worker", 1, time.Minute)
+
+	p.reapOnce(ctx)
+
+	queued := client.LRange(ctx, testQueue, 0, -1).Val()
+	if len(queued) != 2 {
+		t.Fatalf("queue = %v, want the two jobs of the dead worker", queued)
+	}
+	if client.SIsMember(ctx, WorkersKey, "dead-worker").Val() {
+		t.Error("dead worker is still registered")
+	}
+	if !client.SIsMember(ctx, WorkersKey, "live-worker").Val() {
+		t.Error("live worker was unregistered")
+	}
+	if n := client.LLen(ctx, config.ProcessingQueueKey(testQueue, "live-worker")).Val(); n != 1 {
+		t.Errorf("live worker has %d jobs in processing, want 1", n)
+	}
+}
+
+func TestExhaustedJobIsDeadLettered(t *testing.T) {
+	p, client := newTestProcessor(t, func(ctx context.Context, job *models.Job) (string, error) {
+		return "", errors.New("boom")
+	})
+	ctx := context.Background()
+
+	// 重试次数已经用完
+	job := newTestJob(t)
+	job.RetryCount = 1
+	p.run(job)
+
+	status := loadStatus(t, client, job.ID)
+	if status.Status != config.TaskStatusFailed || status.FailedAt == nil || status.LastError != "boom" {
+		t.Fatalf("status = %+v, want failed with error and time", status)
+	}
+	if n := client.ZCard(ctx, RetryQueue).Val(); n != 0 {
+		t.Fatalf("retry queue has %d jobs, want 0", n)
+	}
+	payloads := client.LRange(ctx, DeadLetterQueue, 0, -1).Val()
+	if len(payloads) != 1 {
+		t.Fatalf("dead letter queue = %v, want one job", payloads)
+	}
+	var dead models.Job
+	if err := dead.FromJSON([]byte(payloads[0])); err != nil || dead.ID != job.ID {
+		t.Fatalf("dead letter = %+v, %v; want %s", dead, err, job.ID)
+	}
+
+	// 未注册类型的任务直接进入死信队列
+	unknown := newTestJob(t)
+	unknown.Type = "unknown"
+	p.run(unknown)
+	if n := client.LLen(ctx, DeadLetterQueue).Val(); n != 2 {
+		t.Fatalf("dead letter queue has %d jobs, want 2", n)
+	}
+}
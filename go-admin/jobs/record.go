@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"go-admin/config"
+	"go-admin/database"
+	"go-admin/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// finalStatuses 已结束的任务状态，超过保留时长后删除
+var finalStatuses = []string{config.TaskStatusCompleted, config.TaskStatusFailed, config.TaskStatusCancelled}
+
+// SaveRecord 保存任务历史记录，失败时只记录日志，不影响任务处理
+func SaveRecord(job *models.Job) {
+	record := models.NewTaskRecord(job)
+	err := database.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"status", "owner_id", "image_id", "payload", "retry_count",
+			"last_error", "next_attempt_at", "failed_at", "updated_at",
+		}),
+	}).Create(record).Error
+	if err != nil {
+		log.Printf("Failed to save record for job %s: %v", job.ID, err)
+	}
+}
+
+// deleteRecord 删除未能入队的任务的历史记录
+func deleteRecord(id string) {
+	if err := database.DB.Delete(&models.TaskRecord{}, "id = ?", id).Error; err != nil {
+		log.Printf("Failed to delete record for job %s: %v", id, err)
+	}
+}
+
+// pruneRecords 删除超过保留时长的已结束任务记录
+func pruneRecords(retention time.Duration) {
+	result := database.DB.
+		Where("status IN ? AND updated_at < ?", finalStatuses, time.Now().Add(-retention)).
+		Delete(&models.TaskRecord{})
+	if result.Error != nil {
+		log.Printf("Failed to prune task records: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Pruned %d task records", result.RowsAffected)
+	}
+}
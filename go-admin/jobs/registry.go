@@ -0,0 +1,133 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-admin/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrUnknownJobType 任务类型没有注册
+var ErrUnknownJobType = errors.New("unknown job type")
+
+// statusTTL 任务状态保留时间
+const statusTTL = 24 * time.Hour
+
+// Handler 任务处理函数，成功时返回结果信息，返回错误时按退避策略重试。
+// 任务可能被重复投递，处理函数必须是幂等的
+type Handler func(ctx context.Context, job *models.Job) (string, error)
+
+// Definition 任务类型定义
+type Definition struct {
+	Type    string  // 任务类型
+	Queue   string  // 任务队列（Redis列表），多个类型可以共用
+	Channel string  // 发布任务结果的频道
+	Handler Handler // 处理函数
+}
+
+// Periodic 周期任务，随处理器启动和停止
+type Periodic struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context)
+}
+
+// Registry 任务类型注册表
+type Registry struct {
+	mu          sync.RWMutex
+	definitions map[string]Definition
+	queues      []string
+	periodic    []Periodic
+}
+
+// NewRegistry 创建任务类型注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		definitions: make(map[string]Definition),
+	}
+}
+
+// Register 注册任务类型，重复注册同一类型会panic
+func (r *Registry) Register(definition Definition) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.definitions[definition.Type]; exists {
+		panic(fmt.Sprintf("jobs: job type %q registered twice", definition.Type))
+	}
+	r.definitions[definition.Type] = definition
+
+	for _, queue := range r.queues {
+		if queue == definition.Queue {
+			return
+		}
+	}
+	r.queues = append(r.queues, definition.Queue)
+}
+
+// Every 注册周期任务
+func (r *Registry) Every(name string, interval time.Duration, run func(ctx context.Context)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.periodic = append(r.periodic, Periodic{Name: name, Interval: interval, Run: run})
+}
+
+// Lookup 获取任务类型定义
+func (r *Registry) Lookup(jobType string) (Definition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	definition, ok := r.definitions[jobType]
+	return definition, ok
+}
+
+// Queues 获取全部任务队列
+func (r *Registry) Queues() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.queues...)
+}
+
+// Periodic 获取全部周期任务
+func (r *Registry) Periodic() []Periodic {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Periodic(nil), r.periodic...)
+}
+
+// Enqueue 将任务放入对应的队列并记录状态
+func Enqueue(ctx context.Context, redisClient *redis.Client, registry *Registry, job *models.Job) error {
+	definition, ok := registry.Lookup(job.Type)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownJobType, job.Type)
+	}
+
+	jobJSON, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s job: %v", job.Type, err)
+	}
+
+	// 先保存历史记录，避免覆盖处理进程已经更新的状态
+	SaveRecord(job)
+
+	_, err = redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, StatusKey(job.ID), jobJSON, statusTTL)
+		pipe.RPush(ctx, definition.Queue, jobJSON)
+		return nil
+	})
+	if err != nil {
+		deleteRecord(job.ID)
+		return err
+	}
+	return nil
+}
+
+// StatusKey 任务状态键
+func StatusKey(jobID string) string {
+	return fmt.Sprintf("task:status:%s", jobID)
+}
@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-admin/config"
+	"go-admin/database"
+	"go-admin/models"
+	"go-admin/testutil"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.Register(Definition{Type: "delete", Queue: "queue:a"})
+	r.Register(Definition{Type: "expire", Queue: "queue:a"})
+	r.Register(Definition{Type: "report", Queue: "queue:b"})
+	r.Every("tick", time.Second, func(context.Context) {})
+
+	// 多个类型共用的队列只出现一次
+	if queues := r.Queues(); !reflect.DeepEqual(queues, []string{"queue:a", "queue:b"}) {
+		t.Fatalf("Queues() = %v", queues)
+	}
+	if definition, ok := r.Lookup("expire"); !ok || definition.Queue != "queue:a" {
+		t.Fatalf("Lookup(expire) = %+v, %v", definition, ok)
+	}
+	if _, ok := r.Lookup("missing"); ok {
+		t.Fatal("Lookup(missing) succeeded")
+	}
+	if periodic := r.Periodic(); len(periodic) != 1 || periodic[0].Name != "tick" {
+		t.Fatalf("Periodic() = %+v", periodic)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering a type twice did not panic")
+		}
+	}()
+	r.Register(Definition{Type: "delete", Queue: "queue:c"})
+}
+
+func TestEnqueue(t *testing.T) {
+	cfg := testutil.Config(t)
+	testutil.NewDB(t, cfg)
+	_, client := testutil.NewRedis(t)
+	ctx := context.Background()
+
+	r := NewRegistry()
+	r.Register(Definition{Type: "test", Queue: testQueue})
+
+	job := newTestJob(t)
+	if err := Enqueue(ctx, client, r, job); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if n := client.LLen(ctx, testQueue).Val(); n != 1 {
+		t.Fatalf("queue has %d jobs, want 1", n)
+	}
+	if status := loadStatus(t, client, job.ID); status.Status != config.TaskStatusPending {
+		t.Fatalf("status = %s, want pending", status.Status)
+	}
+	var record models.TaskRecord
+	if err := database.DB.First(&record, "id = ?", job.ID).Error; err != nil {
+		t.Fatalf("load record: %v", err)
+	}
+
+	// 未注册的类型不会入队
+	unknown := newTestJob(t)
+	unknown.Type = "unknown"
+	if err := Enqueue(ctx, client, r, unknown); !errors.Is(err, ErrUnknownJobType) {
+		t.Fatalf("Enqueue(unknown) = %v, want ErrUnknownJobType", err)
+	}
+	if n := client.LLen(ctx, testQueue).Val(); n != 1 {
+		t.Fatalf("queue has %d jobs, want 1", n)
+	}
+}
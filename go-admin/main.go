@@ -1,27 +1,64 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"go-admin/config"
 	"go-admin/database"
+	"go-admin/events"
 	"go-admin/handlers"
+	"go-admin/jobs"
 	"go-admin/middleware"
 	"go-admin/routes"
+	"go-admin/storage"
 	"go-admin/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// 子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "config":
+			os.Exit(runConfigCommand(os.Args[2:]))
+		case "migrate":
+			os.Exit(runMigrateCommand(os.Args[2:]))
+		}
+	}
+
 	// 加载配置
-	cfg := config.LoadConfig()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		log.Fatal("Failed to load config: ", err)
+	}
+	if cfg.IsProduction() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	// 监听配置修改，部分配置可以热加载
+	settings := config.NewStore(cfg, os.Args[1:])
+	settings.Watch()
 
 	// 创建 Gin 引擎
-	r := gin.Default()
+	r := gin.New()
 
-	// 添加中间件
-	r.Use(gin.Logger())
+	// 添加中间件，请求日志隐藏实时事件连接票据
+	r.Use(middleware.RequestLogger("ticket", "access_token"))
 	r.Use(gin.Recovery())
 	r.Use(middleware.CORSMiddleware())
 
@@ -29,38 +66,249 @@ func main() {
 	if err := config.InitRedis(&cfg.Redis); err != nil {
 		log.Fatal("Failed to initialize Redis:", err)
 	}
-	defer config.CloseRedis()
 
 	// 创建JWT管理器
-	jwtManager := utils.NewJWTManager(cfg.JWT.Secret, cfg.JWT.ExpireTime)
+	jwtManager := utils.NewJWTManager(cfg.JWT.Secret, cfg.JWT.AccessExpireTime, cfg.JWT.RefreshExpireTime, config.RedisClient)
+
+	// 创建密码哈希器
+	passwordHasher := utils.NewBcryptHasher(0)
 
 	// 初始化数据库
-	if err := database.InitDatabase(cfg); err != nil {
+	if err := database.InitDatabase(cfg, passwordHasher); err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
 
 	// 创建用户服务
-	userService := handlers.NewUserService()
+	userService := handlers.NewUserService(passwordHasher, jwtManager)
+
+	// 创建存储后端
+	storageBackend, err := storage.New(&cfg.Storage)
+	if err != nil {
+		log.Fatal("Failed to initialize storage:", err)
+	}
+
+	// 创建任务注册表
+	jobRegistry := jobs.NewRegistry()
 
 	// 创建图片服务
-	imageService := handlers.NewImageService(config.RedisClient)
+	imageService := handlers.NewImageService(config.RedisClient, storageBackend, settings, jobRegistry)
 
-	// 创建Redis任务处理器
-	taskHandler := handlers.NewRedisTaskHandler(config.RedisClient, imageService)
-	taskHandler.StartTaskProcessor()
-	defer taskHandler.StopTaskProcessor()
+	// 注册图片任务并启动后台任务处理器
+	handlers.RegisterImageJobs(jobRegistry, config.RedisClient, imageService, storageBackend)
+	jobProcessor := jobs.NewProcessor(config.RedisClient, jobRegistry, settings)
+	jobProcessor.Start()
+
+	// 选举清理任务领导者，多实例部署时只有一个实例执行定期清理
+	cleanupLeader := utils.NewLeaderElector(config.RedisClient, "cleanup", cfg.Task.LeaderTTL)
+	cleanupLeader.SetTokenFloor(database.FenceToken)
+	cleanupLeader.Start()
 
 	// 启动图片清理调度器
-	imageService.StartCleanupScheduler()
+	imageService.StartCleanupScheduler(cleanupLeader)
+
+	// 启动实时事件分发
+	eventHub := events.NewHub(config.RedisClient)
+	eventHub.Start()
+
+	// 创建后台任务服务
+	taskService := handlers.NewTaskService(config.RedisClient, jobProcessor)
 
 	// 设置路由
-	routes.SetupRoutes(r, jwtManager, userService, imageService)
+	readiness := &utils.Readiness{}
+	routes.SetupRoutes(r, jwtManager, userService, imageService, taskService, eventHub, cleanupLeader, readiness)
 
 	// 启动服务器
 	addr := cfg.Server.Host + ":" + cfg.Server.Port
-	log.Printf("Go Admin API server starting on %s", addr)
-	if err := r.Run(addr); err != nil {
-		log.Fatal("Failed to start server:", err)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	// 实时事件是长连接，停止时关闭订阅使其结束，否则会一直阻塞 Shutdown
+	server.RegisterOnShutdown(eventHub.Stop)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Go Admin API server starting on %s", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		log.Println("Shutting down server...")
+	case err := <-serverErr:
+		log.Printf("Failed to start server: %v", err)
+		exitCode = 1
+	}
+	// 恢复默认信号处理，停止过程中再次收到信号时立即退出
+	stop()
+
+	steps := append(backgroundSteps(imageService, cleanupLeader, jobProcessor, settings),
+		shutdownStep{name: "close database", run: database.Close},
+		shutdownStep{name: "close Redis", run: config.CloseRedis},
+	)
+	shutdown(cfg.Server, server, readiness, steps)
+	os.Exit(exitCode)
+}
+
+// httpServer 停止时使用的HTTP服务器方法
+type httpServer interface {
+	Shutdown(ctx context.Context) error
+}
+
+// shutdownStep HTTP服务器停止后按顺序执行的停止步骤
+type shutdownStep struct {
+	name string
+	run  func() error
+}
+
+// backgroundSteps 后台组件的停止步骤：先停止定期清理并释放领导权，其他实例可以立即接管，
+// 再等待正在执行的后台任务完成，最后停止监听配置修改
+func backgroundSteps(imageService *handlers.ImageServiceImpl, cleanupLeader *utils.LeaderElector,
+	jobProcessor *jobs.Processor, settings *config.Store) []shutdownStep {
+	return []shutdownStep{
+		{name: "stop image cleanup scheduler", run: func() error { imageService.StopCleanupScheduler(); return nil }},
+		{name: "release cleanup leadership", run: func() error { cleanupLeader.Stop(); return nil }},
+		{name: "stop job processor", run: func() error { jobProcessor.Stop(); return nil }},
+		{name: "stop config watcher", run: func() error { settings.Stop(); return nil }},
+	}
+}
+
+// shutdown 按顺序停止服务：先标记为未就绪并停止接受新请求，等待处理中的请求完成，
+// 再依次执行 steps，某一步失败时记录日志并继续
+func shutdown(serverConfig config.ServerConfig, server httpServer, readiness *utils.Readiness, steps []shutdownStep) {
+	// 健康检查返回503，等待负载均衡摘除实例
+	readiness.Drain()
+	if serverConfig.DrainDelay > 0 {
+		log.Printf("Waiting %s for load balancers to drain", serverConfig.DrainDelay)
+		time.Sleep(serverConfig.DrainDelay)
+	}
+
+	// 停止接受新连接，等待处理中的请求完成
+	ctx, cancel := context.WithTimeout(context.Background(), serverConfig.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("HTTP server did not shut down cleanly: %v", err)
+	} else {
+		log.Println("HTTP server stopped")
+	}
+
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			log.Printf("Failed to %s: %v", step.name, err)
+		}
+	}
+	log.Println("Server stopped")
+}
+
+// runConfigCommand 处理 config 子命令，返回进程退出码。
+// config print 按与启动服务相同的方式加载配置，输出隐藏敏感字段后的最终配置
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "Usage: go-admin config print [flags]")
+		fmt.Fprintln(os.Stderr)
+		config.PrintDefaults(os.Stderr)
+		return 2
+	}
+
+	cfg, err := config.Load(args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	out, err := cfg.Redacted().YAML()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	os.Stdout.Write(out)
+	return 0
+}
+
+// runMigrateCommand 处理 migrate 子命令，返回进程退出码。
+// migrate up 执行全部未执行的迁移，migrate down [n] 回滚最近的 n 个迁移（默认 1 个），migrate status 列出迁移状态
+func runMigrateCommand(args []string) int {
+	if len(args) == 0 || (args[0] != "up" && args[0] != "down" && args[0] != "status") {
+		fmt.Fprintln(os.Stderr, "Usage: go-admin migrate up|down [n]|status [flags]")
+		fmt.Fprintln(os.Stderr)
+		config.PrintDefaults(os.Stderr)
+		return 2
+	}
+	action, args := args[0], args[1:]
+
+	steps := 1
+	if action == "down" && len(args) > 0 {
+		if n, err := strconv.Atoi(args[0]); err == nil {
+			if n < 1 {
+				fmt.Fprintln(os.Stderr, "migrate down: n must be at least 1")
+				return 2
+			}
+			steps, args = n, args[1:]
+		}
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := database.Connect(&cfg.Database); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer database.Close()
+
+	switch action {
+	case "up":
+		applied, err := database.MigrateUp(cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database schema is up to date")
+		}
+	case "down":
+		rolledBack, err := database.MigrateDown(steps)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("No migrations to roll back")
+		}
+	case "status":
+		statuses, err := database.MigrationStatuses()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			if !status.Known {
+				appliedAt += " (unknown to this build)"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		w.Flush()
 	}
+	return 0
 }
- 
\ No newline at end of file
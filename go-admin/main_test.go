@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"go-admin/config"
+	"go-admin/handlers"
+	"go-admin/jobs"
+	"go-admin/models"
+	"go-admin/storage"
+	"go-admin/testutil"
+	"go-admin/utils"
+)
+
+// recordingServer 记录 Shutdown 开始时的就绪状态
+type recordingServer struct {
+	*http.Server
+	readyAtShutdown bool
+	shutdownAt      time.Time
+	shutdownDone    time.Time
+}
+
+func (s *recordingServer) Shutdown(ctx context.Context) error {
+	s.shutdownAt = time.Now()
+	err := s.Server.Shutdown(ctx)
+	s.shutdownDone = time.Now()
+	return err
+}
+
+// waitUntil 等待条件满足
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestShutdownDrainsBeforeServerShutdown(t *testing.T) {
+	readiness := &utils.Readiness{}
+	inFlight := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		if !readiness.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(inFlight)
+		<-release
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := &recordingServer{Server: &http.Server{Handler: mux}}
+	go server.Serve(listener)
+	url := "http://" + listener.Addr().String()
+
+	// 停止时仍有未完成的请求
+	go http.Get(url + "/slow")
+	<-inFlight
+
+	serverConfig := config.ServerConfig{DrainDelay: 100 * time.Millisecond, ShutdownTimeout: 200 * time.Millisecond}
+	var stepAt time.Time
+	steps := []shutdownStep{{name: "record", run: func() error {
+		server.readyAtShutdown = readiness.Ready()
+		stepAt = time.Now()
+		return nil
+	}}}
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		shutdown(serverConfig, server, readiness, steps)
+	}()
+
+	// 等待摘除期间仍接受连接，健康检查返回503
+	resp, err := http.Get(url + "/ready")
+	if err != nil {
+		t.Fatalf("readiness check during drain: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("readiness during drain = %d, want 503", resp.StatusCode)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not return")
+	}
+	if server.readyAtShutdown {
+		t.Error("still ready when the server was shut down")
+	}
+	if drained := server.shutdownAt.Sub(start); drained < serverConfig.DrainDelay {
+		t.Errorf("server shut down %s after draining started, want at least %s", drained, serverConfig.DrainDelay)
+	}
+	// 未完成的请求不会让停止超过期限
+	if waited := server.shutdownDone.Sub(server.shutdownAt); waited < serverConfig.ShutdownTimeout || waited > serverConfig.ShutdownTimeout+time.Second {
+		t.Errorf("server shutdown took %s, want about %s", waited, serverConfig.ShutdownTimeout)
+	}
+	if stepAt.Before(server.shutdownDone) {
+		t.Error("shutdown steps ran before the server was shut down")
+	}
+}
+
+func TestShutdownStopsBackgroundComponentsInOrder(t *testing.T) {
+	cfg := testutil.Config(t)
+	cfg.Task.Workers = 1
+	cfg.Task.ShutdownTimeout = 100 * time.Millisecond
+	testutil.NewDB(t, cfg)
+	_, client := testutil.NewRedis(t)
+	ctx := context.Background()
+	settings := config.NewStore(cfg, nil)
+	backend, err := storage.New(&cfg.Storage)
+	if err != nil {
+		t.Fatalf("storage.New: %v", err)
+	}
+
+	// 停止时有一个任务正在执行，直到被取消才返回
+	var startOnce sync.Once
+	started := make(chan struct{})
+	registry := jobs.NewRegistry()
+	registry.Register(jobs.Definition{Type: "test", Queue: "test:queue", Handler: func(ctx context.Context, job *models.Job) (string, error) {
+		startOnce.Do(func() { close(started) })
+		<-ctx.Done()
+		return "", ctx.Err()
+	}})
+	jobProcessor := jobs.NewProcessor(client, registry, settings)
+	jobProcessor.Start()
+	job, err := models.NewJob("test", map[string]int{"n": 1})
+	if err != nil {
+		t.Fatalf("NewJob: %v", err)
+	}
+	if err := jobs.Enqueue(ctx, client, registry, job); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	<-started
+
+	cleanupLeader := utils.NewLeaderElector(client, "cleanup", cfg.Task.LeaderTTL)
+	cleanupLeader.Start()
+	waitUntil(t, "leadership", cleanupLeader.IsLeader)
+	imageService := handlers.NewImageService(client, backend, settings, registry)
+	imageService.StartCleanupScheduler(cleanupLeader)
+	settings.Watch()
+
+	// 记录每一步完成时各组件的状态
+	var order []string
+	leaderAfterScheduler := false
+	steps := backgroundSteps(imageService, cleanupLeader, jobProcessor, settings)
+	for i := range steps {
+		step := steps[i]
+		steps[i].run = func() error {
+			err := step.run()
+			order = append(order, step.name)
+			switch step.name {
+			case "stop image cleanup scheduler":
+				leaderAfterScheduler = cleanupLeader.IsLeader()
+			case "release cleanup leadership":
+				if cleanupLeader.IsLeader() {
+					t.Error("still leader after releasing leadership")
+				}
+			case "stop job processor":
+				if n := client.SCard(ctx, jobs.WorkersKey).Val(); n != 0 {
+					t.Errorf("%d workers registered after the processor stopped", n)
+				}
+				if n := client.LLen(ctx, "test:queue").Val(); n != 1 {
+					t.Errorf("queue has %d jobs after the processor stopped, want the aborted job requeued", n)
+				}
+			}
+			return err
+		}
+	}
+	steps = append(steps, shutdownStep{name: "failing step", run: func() error {
+		order = append(order, "failing step")
+		return errors.New("boom")
+	}}, shutdownStep{name: "last step", run: func() error {
+		order = append(order, "last step")
+		return nil
+	}})
+
+	server := &http.Server{}
+	serverConfig := config.ServerConfig{ShutdownTimeout: time.Second}
+	start := time.Now()
+	shutdown(serverConfig, server, &utils.Readiness{}, steps)
+	elapsed := time.Since(start)
+
+	want := []string{
+		"stop image cleanup scheduler",
+		"release cleanup leadership",
+		"stop job processor",
+		"stop config watcher",
+		"failing step",
+		"last step",
+	}
+	if len(order) != len(want) {
+		t.Fatalf("steps = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("steps = %v, want %v", order, want)
+		}
+	}
+	// 清理调度器停止时仍持有领导权，不会有清理在失去领导权后执行
+	if !leaderAfterScheduler {
+		t.Error("leadership was lost before the cleanup scheduler stopped")
+	}
+	// 正在执行的任务在任务停止期限后被取消
+	if limit := serverConfig.ShutdownTimeout + cfg.Task.ShutdownTimeout; elapsed > limit {
+		t.Errorf("shutdown took %s, want at most %s", elapsed, limit)
+	}
+}
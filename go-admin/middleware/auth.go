@@ -34,9 +34,37 @@ func AuthMiddleware(jwtManager *utils.JWTManager) gin.HandlerFunc {
 			return
 		}
 
-		// 将用户信息存储到上下文中
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
+		setClaims(c, claims)
 		c.Next()
 	}
 }
+
+// StreamTicketMiddleware 通过查询参数中的一次性票据认证。
+// 浏览器的EventSource无法设置请求头，只在实时事件路由上使用，票据由 POST /events/ticket 签发
+func StreamTicketMiddleware(jwtManager *utils.JWTManager, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ticket := c.Query(param)
+		if ticket == "" {
+			utils.Unauthorized(c, "Stream ticket is required")
+			c.Abort()
+			return
+		}
+
+		claims, err := jwtManager.RedeemStreamTicket(ticket)
+		if err != nil {
+			utils.Unauthorized(c, "Invalid or expired stream ticket")
+			c.Abort()
+			return
+		}
+
+		setClaims(c, claims)
+		c.Next()
+	}
+}
+
+// setClaims 将用户信息存储到上下文中
+func setClaims(c *gin.Context, claims *utils.Claims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("claims", claims)
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamTicketMiddleware(t *testing.T) {
+	jwtManager := newTestJWTManager(t)
+	router := gin.New()
+	router.GET("/events", StreamTicketMiddleware(jwtManager, "ticket"), func(c *gin.Context) {
+		if claims := c.MustGet("claims").(*utils.Claims); claims.UserID != 42 {
+			t.Errorf("claims.UserID = %d, want 42", claims.UserID)
+		}
+		c.Status(http.StatusOK)
+	})
+
+	claims, err := jwtManager.ValidateToken(issueToken(t, jwtManager, "images:read"))
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	ticket, err := jwtManager.IssueStreamTicket(claims)
+	if err != nil {
+		t.Fatalf("IssueStreamTicket: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"valid ticket", "/events?ticket=" + ticket, http.StatusOK},
+		// 票据只能使用一次
+		{"reused ticket", "/events?ticket=" + ticket, http.StatusUnauthorized},
+		{"unknown ticket", "/events?ticket=unknown", http.StatusUnauthorized},
+		{"missing ticket", "/events", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if w := doRequest(router, http.MethodGet, tt.path, ""); w.Code != tt.want {
+			t.Fatalf("%s: GET %s = %d, want %d", tt.name, tt.path, w.Code, tt.want)
+		}
+	}
+
+	// 访问令牌不能代替票据
+	token := issueToken(t, jwtManager, "images:read")
+	if w := doRequest(router, http.MethodGet, "/events?ticket="+token, token); w.Code != http.StatusUnauthorized {
+		t.Fatalf("GET /events with an access token = %d, want 401", w.Code)
+	}
+}
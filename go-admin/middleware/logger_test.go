@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedactQuery(t *testing.T) {
+	params := []string{"ticket", "access_token"}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/v1/events", "/api/v1/events"},
+		{"/api/v1/events?ticket=secret", "/api/v1/events?ticket=REDACTED"},
+		{"/api/v1/events?page=2&ticket=secret", "/api/v1/events?page=2&ticket=REDACTED"},
+		{"/api/v1/events?access_token=a&ticket=b", "/api/v1/events?access_token=REDACTED&ticket=REDACTED"},
+		{"/api/v1/images?page=2", "/api/v1/images?page=2"},
+		// 无法解析的查询整体隐藏
+		{"/api/v1/events?ticket=%zz", "/api/v1/events?REDACTED"},
+	}
+	for _, tt := range tests {
+		if got := redactQuery(tt.path, params); got != tt.want {
+			t.Errorf("redactQuery(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRequestLoggerRedactsTicket(t *testing.T) {
+	var buf bytes.Buffer
+	defaultWriter := gin.DefaultWriter
+	gin.DefaultWriter = &buf
+	defer func() { gin.DefaultWriter = defaultWriter }()
+
+	router := gin.New()
+	router.Use(RequestLogger("ticket"))
+	router.GET("/events", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	doRequest(router, http.MethodGet, "/events?ticket=very-secret-ticket&page=1", "")
+
+	logged := buf.String()
+	if strings.Contains(logged, "very-secret-ticket") {
+		t.Fatalf("log contains the ticket: %s", logged)
+	}
+	if !strings.Contains(logged, "/events?page=1&ticket=REDACTED") || !strings.Contains(logged, "200") {
+		t.Fatalf("unexpected log line: %s", logged)
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission 权限校验中间件，需在AuthMiddleware之后使用
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("claims")
+		if !exists {
+			utils.Unauthorized(c, "Authentication required")
+			c.Abort()
+			return
+		}
+
+		claims := value.(*utils.Claims)
+		if !claims.HasPermission(permission) {
+			utils.Forbidden(c, "Permission denied: "+permission)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
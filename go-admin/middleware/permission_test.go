@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-admin/database"
+	"go-admin/testutil"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestJWTManager 创建使用内存Redis的令牌管理器
+func newTestJWTManager(t *testing.T) *utils.JWTManager {
+	t.Helper()
+	_, client := testutil.NewRedis(t)
+	return utils.NewJWTManager("test-secret", 15*time.Minute, time.Hour, client)
+}
+
+// issueToken 签发拥有指定权限的访问令牌
+func issueToken(t *testing.T, jwtManager *utils.JWTManager, permissions ...string) string {
+	t.Helper()
+	tokens, err := jwtManager.GenerateTokenPair(&utils.Principal{
+		UserID:      42,
+		Username:    "tester",
+		Roles:       []string{"member"},
+		Permissions: permissions,
+	})
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+	return tokens.AccessToken
+}
+
+// doRequest 发送请求并返回响应
+func doRequest(router http.Handler, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequirePermission(t *testing.T) {
+	jwtManager := newTestJWTManager(t)
+
+	// 没有数据库连接：权限只能来自令牌声明，按请求查库会直接失败
+	database.DB = nil
+
+	router := gin.New()
+	protected := router.Group("/api", AuthMiddleware(jwtManager))
+	protected.GET("/images", RequirePermission("images:read"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	protected.DELETE("/users/:id", RequirePermission("users:delete"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	// 未经认证中间件的路由缺少声明
+	router.GET("/unauthenticated", RequirePermission("images:read"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	member := issueToken(t, jwtManager, "images:read", "images:upload")
+	admin := issueToken(t, jwtManager, "images:read", "users:delete")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"permission present", http.MethodGet, "/api/images", member, http.StatusOK},
+		{"permission missing", http.MethodDelete, "/api/users/1", member, http.StatusForbidden},
+		{"other permission present", http.MethodDelete, "/api/users/1", admin, http.StatusNoContent},
+		{"no token", http.MethodGet, "/api/images", "", http.StatusUnauthorized},
+		{"invalid token", http.MethodGet, "/api/images", "not-a-token", http.StatusUnauthorized},
+		{"no claims", http.MethodGet, "/unauthenticated", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := doRequest(router, tt.method, tt.path, tt.token); w.Code != tt.want {
+				t.Fatalf("%s %s = %d, want %d (%s)", tt.method, tt.path, w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequirePermissionUsesTokenSnapshot(t *testing.T) {
+	jwtManager := newTestJWTManager(t)
+	database.DB = nil
+
+	handled := 0
+	router := gin.New()
+	router.GET("/images", AuthMiddleware(jwtManager), RequirePermission("images:read"), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*utils.Claims)
+		if claims.UserID != 42 {
+			t.Errorf("claims.UserID = %d, want 42", claims.UserID)
+		}
+		handled++
+		c.Status(http.StatusOK)
+	})
+
+	// 同一令牌的多次请求都只依据令牌中的权限判断
+	token := issueToken(t, jwtManager, "images:read")
+	for i := 0; i < 3; i++ {
+		if w := doRequest(router, http.MethodGet, "/images", token); w.Code != http.StatusOK {
+			t.Fatalf("request %d = %d, want 200", i, w.Code)
+		}
+	}
+	if handled != 3 {
+		t.Fatalf("handler ran %d times, want 3", handled)
+	}
+}
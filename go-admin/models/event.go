@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// 事件类型
+const (
+	EventTaskProcessing = "task.processing" // 任务开始处理
+	EventTaskRetrying   = "task.retrying"   // 任务失败，等待重试
+	EventTaskCompleted  = "task.completed"  // 任务成功
+	EventTaskFailed     = "task.failed"     // 重试耗尽，进入死信队列
+	EventTaskCancelled  = "task.cancelled"  // 任务被取消
+	EventImageUploaded  = "image.uploaded"  // 图片上传
+	EventImageExpired   = "image.expired"   // 图片过期
+	EventImageDeleted   = "image.deleted"   // 图片删除
+	EventStreamClosed   = "stream.closed"   // 访问令牌过期或会话被吊销，服务端随即关闭连接
+)
+
+// Event 推送给前端的实时事件
+type Event struct {
+	Type      string    `json:"type"`
+	UserID    int       `json:"user_id,omitempty"` // 事件所属用户，只推送给该用户和管理员
+	TaskID    string    `json:"task_id,omitempty"`
+	TaskType  string    `json:"task_type,omitempty"`
+	ImageID   int       `json:"image_id,omitempty"`
+	ImageCode string    `json:"image_code,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Time      time.Time `json:"time"`
+}
@@ -1,22 +1,29 @@
 package models
 
 import (
+	"strings"
 	"time"
 )
 
 // Image 图片模型
 type Image struct {
-	ID         int       `json:"id" gorm:"primaryKey"`
-	ImageCode  string    `json:"image_code" gorm:"uniqueIndex;size:50;not null"` // 唯一图片码
-	FileName   string    `json:"file_name" gorm:"size:255;not null"`             // 原始文件名
-	FilePath   string    `json:"file_path" gorm:"size:500;not null"`             // 存储路径
-	FileSize   int64     `json:"file_size" gorm:"not null"`                      // 文件大小(字节)
-	FileType   string    `json:"file_type" gorm:"size:50;not null"`              // 文件类型
-	UploadTime time.Time `json:"upload_time" gorm:"autoCreateTime"`              // 上传时间
-	ExpireTime time.Time `json:"expire_time" gorm:"not null"`                    // 过期时间
-	Status     string    `json:"status" gorm:"size:20;default:'active'"`         // 状态: active, expired, deleted
-	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID          int       `json:"id" gorm:"primaryKey"`
+	ImageCode   string    `json:"image_code" gorm:"uniqueIndex;size:50;not null"` // 唯一图片码
+	OwnerID     int       `json:"owner_id" gorm:"index;not null;default:0"`       // 上传者用户ID
+	FileName    string    `json:"file_name" gorm:"size:255;not null"`             // 原始文件名
+	FilePath    string    `json:"file_path" gorm:"size:500;not null"`             // 存储路径
+	FileSize    int64     `json:"file_size" gorm:"not null"`                      // 文件大小(字节)
+	FileType    string    `json:"file_type" gorm:"size:50;not null"`              // 文件类型
+	MimeType    string    `json:"mime_type" gorm:"size:50"`                       // 检测到的MIME类型
+	ContentHash string    `json:"content_hash" gorm:"index;size:64"`              // 内容SHA-256，用作强ETag和去重
+	Width       int       `json:"width"`                                          // 宽度(像素)
+	Height      int       `json:"height"`                                         // 高度(像素)
+	Variants    string    `json:"variants" gorm:"size:255"`                       // 已生成的尺寸变体，逗号分隔
+	UploadTime  time.Time `json:"upload_time" gorm:"autoCreateTime"`              // 上传时间
+	ExpireTime  time.Time `json:"expire_time" gorm:"not null"`                    // 过期时间
+	Status      string    `json:"status" gorm:"size:20;default:'active'"`         // 状态: active, expired, deleted
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 // ImageResponse 图片响应结构
@@ -36,10 +43,16 @@ func (i *Image) ToResponse() ImageResponse {
 	return ImageResponse{
 		ID:            i.ID,
 		ImageCode:     i.ImageCode,
+		OwnerID:       i.OwnerID,
 		FileName:      i.FileName,
 		FilePath:      i.FilePath,
 		FileSize:      i.FileSize,
 		FileType:      i.FileType,
+		MimeType:      i.MimeType,
+		ContentHash:   i.ContentHash,
+		Width:         i.Width,
+		Height:        i.Height,
+		Variants:      i.VariantNames(),
 		UploadTime:    i.UploadTime,
 		ExpireTime:    i.ExpireTime,
 		Status:        i.Status,
@@ -50,14 +63,74 @@ func (i *Image) ToResponse() ImageResponse {
 	}
 }
 
+// ToPublicResponse 转换为公开接口的响应结构
+func (i *Image) ToPublicResponse() PublicImageResponse {
+	response := i.ToResponse()
+	return PublicImageResponse{
+		ID:            response.ID,
+		ImageCode:     response.ImageCode,
+		FileName:      response.FileName,
+		FileSize:      response.FileSize,
+		FileType:      response.FileType,
+		MimeType:      response.MimeType,
+		Width:         response.Width,
+		Height:        response.Height,
+		Variants:      response.Variants,
+		UploadTime:    response.UploadTime,
+		ExpireTime:    response.ExpireTime,
+		Status:        response.Status,
+		RemainingTime: response.RemainingTime,
+		IsExpired:     response.IsExpired,
+	}
+}
+
+// VariantKey 获取尺寸变体的存储键
+func VariantKey(name, filePath string) string {
+	return "variants/" + name + "/" + filePath
+}
+
+// VariantNames 获取已生成的变体名称
+func (i *Image) VariantNames() []string {
+	if i.Variants == "" {
+		return []string{}
+	}
+	return strings.Split(i.Variants, ",")
+}
+
+// HasVariant 判断是否生成了指定变体
+func (i *Image) HasVariant(name string) bool {
+	for _, variant := range i.VariantNames() {
+		if variant == name {
+			return true
+		}
+	}
+	return false
+}
+
+// VariantPaths 获取全部变体的存储键
+func (i *Image) VariantPaths() []string {
+	names := i.VariantNames()
+	paths := make([]string, len(names))
+	for j, name := range names {
+		paths[j] = VariantKey(name, i.FilePath)
+	}
+	return paths
+}
+
 // ImageResponse 图片响应结构
 type ImageResponse struct {
 	ID            int       `json:"id"`
 	ImageCode     string    `json:"image_code"`
+	OwnerID       int       `json:"owner_id"`
 	FileName      string    `json:"file_name"`
 	FilePath      string    `json:"file_path"`
 	FileSize      int64     `json:"file_size"`
 	FileType      string    `json:"file_type"`
+	MimeType      string    `json:"mime_type"`
+	ContentHash   string    `json:"content_hash"`
+	Width         int       `json:"width"`
+	Height        int       `json:"height"`
+	Variants      []string  `json:"variants"`
 	UploadTime    time.Time `json:"upload_time"`
 	ExpireTime    time.Time `json:"expire_time"`
 	Status        string    `json:"status"`
@@ -67,9 +140,28 @@ type ImageResponse struct {
 	UpdatedAt     time.Time `json:"updated_at"`
 }
 
+// PublicImageResponse 公开接口的图片响应，持有图片码即可访问，
+// 不包含上传者、存储路径和内容哈希
+type PublicImageResponse struct {
+	ID            int       `json:"id"`
+	ImageCode     string    `json:"image_code"`
+	FileName      string    `json:"file_name"`
+	FileSize      int64     `json:"file_size"`
+	FileType      string    `json:"file_type"`
+	MimeType      string    `json:"mime_type"`
+	Width         int       `json:"width"`
+	Height        int       `json:"height"`
+	Variants      []string  `json:"variants"`
+	UploadTime    time.Time `json:"upload_time"`
+	ExpireTime    time.Time `json:"expire_time"`
+	Status        string    `json:"status"`
+	RemainingTime int64     `json:"remaining_time"` // 剩余时间（毫秒）
+	IsExpired     bool      `json:"is_expired"`
+}
+
 // UploadImageRequest 上传图片请求
 type UploadImageRequest struct {
-	ExpireValue int    `json:"expire_value" binding:"required,min=1"` // 过期时间值
+	ExpireValue int    `json:"expire_value" binding:"required,min=1"`                   // 过期时间值
 	ExpireUnit  string `json:"expire_unit" binding:"required,oneof=minutes hours days"` // 过期时间单位: minutes, hours, days
 }
 
@@ -78,3 +170,21 @@ type ImageListResponse struct {
 	Total int             `json:"total"`
 	Items []ImageResponse `json:"items"`
 }
+
+// ImageJobPayload 图片删除和过期任务的参数
+type ImageJobPayload struct {
+	ImageID      int      `json:"image_id"`
+	ImageCode    string   `json:"image_code"`
+	FilePath     string   `json:"file_path"`
+	VariantPaths []string `json:"variant_paths,omitempty"`
+}
+
+// NewImageJobPayload 创建图片任务参数
+func NewImageJobPayload(image *Image) ImageJobPayload {
+	return ImageJobPayload{
+		ImageID:      image.ID,
+		ImageCode:    image.ImageCode,
+		FilePath:     image.FilePath,
+		VariantPaths: image.VariantPaths(),
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// ImageBlob 去重后的图片内容，内容相同的多条图片记录共享同一份文件
+type ImageBlob struct {
+	ID          int       `json:"id" gorm:"primaryKey"`
+	ContentHash string    `json:"content_hash" gorm:"uniqueIndex;size:64;not null"` // 内容SHA-256
+	FilePath    string    `json:"file_path" gorm:"size:500;not null"`               // 存储键
+	FileSize    int64     `json:"file_size" gorm:"not null"`                        // 文件大小(字节)
+	MimeType    string    `json:"mime_type" gorm:"size:50"`                         // MIME类型
+	Variants    string    `json:"variants" gorm:"size:255"`                         // 已生成的尺寸变体，逗号分隔
+	RefCount    int       `json:"ref_count" gorm:"not null;default:0"`              // 引用该内容的有效图片数
+	Status      string    `json:"status" gorm:"size:20;not null;default:ready"`     // 状态: pending(上传中), ready(已就绪), failed(上传失败)
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// BlobKey 按内容哈希和记录的生成标识生成存储键。
+// 同一内容被删除后再次上传时使用新的存储键，删除旧文件不会影响新上传的文件
+func BlobKey(contentHash, generation, ext string) string {
+	return "blobs/" + contentHash[:2] + "/" + contentHash + "-" + generation + ext
+}
+
+// VariantPaths 获取全部变体的存储键
+func (b *ImageBlob) VariantPaths() []string {
+	image := Image{FilePath: b.FilePath, Variants: b.Variants}
+	return image.VariantPaths()
+}
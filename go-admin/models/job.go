@@ -0,0 +1,99 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go-admin/utils"
+)
+
+// Job 后台任务，Payload为具体任务类型的参数
+type Job struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`               // 任务类型，如 "delete"、"expire"
+	OwnerID       int             `json:"owner_id,omitempty"` // 任务所属用户，用于推送实时事件和限定访问范围
+	ImageID       int             `json:"image_id,omitempty"` // 任务处理的图片
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	RetryCount    int             `json:"retry_count"`
+	Status        string          `json:"status"`
+	LastError     string          `json:"last_error,omitempty"`      // 最近一次失败原因
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"` // 下次重试时间
+	FailedAt      *time.Time      `json:"failed_at,omitempty"`       // 进入死信队列的时间
+}
+
+// JobResult 任务结果
+type JobResult struct {
+	TaskID      string    `json:"task_id"`
+	Type        string    `json:"type"`
+	Success     bool      `json:"success"`
+	Message     string    `json:"message"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// DeadLetterListResponse 死信任务列表响应
+type DeadLetterListResponse struct {
+	Total int   `json:"total"`
+	Items []Job `json:"items"`
+}
+
+// QueueMetrics 队列处理指标，处理计数为当前进程启动以来的累计值
+type QueueMetrics struct {
+	Queue     string `json:"queue"`
+	Workers   int    `json:"workers"`
+	Pending   int64  `json:"pending"`
+	InFlight  int64  `json:"in_flight"`
+	Completed int64  `json:"completed"`
+	Failed    int64  `json:"failed"`
+}
+
+// TaskMetricsResponse 任务指标响应
+type TaskMetricsResponse struct {
+	Queues      []QueueMetrics `json:"queues"`
+	Retrying    int64          `json:"retrying"`
+	DeadLetters int64          `json:"dead_letters"`
+}
+
+// NewJob 创建任务
+func NewJob(jobType string, payload interface{}) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s job payload: %v", jobType, err)
+	}
+	return &Job{
+		ID:         generateTaskID(),
+		Type:       jobType,
+		Payload:    data,
+		CreatedAt:  time.Now(),
+		RetryCount: 0,
+		Status:     "pending",
+	}, nil
+}
+
+// DecodePayload 解析任务参数
+func (j *Job) DecodePayload(v interface{}) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// ToJSON 转换为JSON
+func (j *Job) ToJSON() ([]byte, error) {
+	return json.Marshal(j)
+}
+
+// FromJSON 从JSON解析。早期版本的删除任务没有payload，参数直接位于顶层，
+// 此时将整个任务作为参数，字段名与 ImageJobPayload 一致
+func (j *Job) FromJSON(data []byte) error {
+	if err := json.Unmarshal(data, j); err != nil {
+		return err
+	}
+	if len(j.Payload) == 0 {
+		j.Payload = append(json.RawMessage(nil), data...)
+	}
+	return nil
+}
+
+// generateTaskID 生成任务ID，使用ULID，按字典序排序即按创建时间排序
+func generateTaskID() string {
+	return utils.NewULID()
+}
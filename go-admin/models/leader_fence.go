@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+// LeaderFence 记录各选举已见过的最大防护令牌
+type LeaderFence struct {
+	Name      string    `json:"name" gorm:"primaryKey;size:100"`
+	Token     int64     `json:"token" gorm:"not null;default:0"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+)
+
+// 内置角色
+const (
+	RoleAdmin  = "admin"  // 管理员
+	RoleMember = "member" // 普通成员
+)
+
+// 权限码
+const (
+	PermUsersRead     = "users:read"
+	PermUsersWrite    = "users:write"
+	PermUsersDelete   = "users:delete"
+	PermUsersSessions = "users:sessions"
+	PermRolesManage   = "roles:manage"
+	PermImagesUpload  = "images:upload"
+	PermImagesRead    = "images:read"
+	PermImagesDelete  = "images:delete"
+	PermTasksManage   = "tasks:manage"
+)
+
+// DefaultPermissions 系统内置权限
+var DefaultPermissions = map[string]string{
+	PermUsersRead:     "查看用户",
+	PermUsersWrite:    "创建和编辑用户",
+	PermUsersDelete:   "删除用户",
+	PermUsersSessions: "吊销用户会话",
+	PermRolesManage:   "管理角色分配",
+	PermImagesUpload:  "上传图片",
+	PermImagesRead:    "查看图片",
+	PermImagesDelete:  "删除图片",
+	PermTasksManage:   "管理后台任务",
+}
+
+// DefaultRoles 内置角色及其默认权限
+var DefaultRoles = map[string][]string{
+	RoleAdmin: {
+		PermUsersRead, PermUsersWrite, PermUsersDelete, PermUsersSessions, PermRolesManage,
+		PermImagesUpload, PermImagesRead, PermImagesDelete, PermTasksManage,
+	},
+	RoleMember: {
+		PermImagesUpload, PermImagesRead, PermImagesDelete,
+	},
+}
+
+// Role 角色模型
+type Role struct {
+	ID          int          `json:"id" gorm:"primaryKey"`
+	Name        string       `json:"name" gorm:"uniqueIndex;size:50;not null"`
+	Description string       `json:"description" gorm:"size:255"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions"`
+	CreatedAt   time.Time    `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time    `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Permission 权限模型
+type Permission struct {
+	ID          int       `json:"id" gorm:"primaryKey"`
+	Code        string    `json:"code" gorm:"uniqueIndex;size:100;not null"` // 如 images:delete
+	Description string    `json:"description" gorm:"size:255"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// AssignRolesRequest 分配角色请求
+type AssignRolesRequest struct {
+	Roles []string `json:"roles" binding:"required,min=1"`
+}
+
+// PermissionCodes 获取角色的权限码
+func (r *Role) PermissionCodes() []string {
+	codes := make([]string, len(r.Permissions))
+	for i, permission := range r.Permissions {
+		codes[i] = permission.Code
+	}
+	return codes
+}
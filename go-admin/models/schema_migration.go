@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// SchemaMigration 已执行的数据库迁移
+type SchemaMigration struct {
+	Version   int       `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `json:"name" gorm:"size:255;not null"`
+	AppliedAt time.Time `json:"applied_at" gorm:"not null"`
+}
+
+// TableName 迁移记录表名
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
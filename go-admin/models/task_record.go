@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TaskRecord 任务历史记录。Redis中的任务状态只保留24小时，数据库中按保留时长保存
+type TaskRecord struct {
+	ID            string          `json:"id" gorm:"primaryKey;size:64"`
+	Type          string          `json:"type" gorm:"size:32;index"`
+	Status        string          `json:"status" gorm:"size:20;index"`
+	OwnerID       int             `json:"owner_id" gorm:"index"`
+	ImageID       int             `json:"image_id,omitempty" gorm:"index"`
+	Payload       json.RawMessage `json:"payload,omitempty" gorm:"type:text"`
+	RetryCount    int             `json:"retry_count"`
+	LastError     string          `json:"last_error,omitempty" gorm:"type:text"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	FailedAt      *time.Time      `json:"failed_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at" gorm:"index"`
+	UpdatedAt     time.Time       `json:"updated_at" gorm:"index"`
+}
+
+// TaskListResponse 任务列表响应
+type TaskListResponse struct {
+	Total int          `json:"total"`
+	Items []TaskRecord `json:"items"`
+}
+
+// NewTaskRecord 根据任务创建历史记录
+func NewTaskRecord(job *Job) *TaskRecord {
+	return &TaskRecord{
+		ID:            job.ID,
+		Type:          job.Type,
+		Status:        job.Status,
+		OwnerID:       job.OwnerID,
+		ImageID:       job.ImageID,
+		Payload:       job.Payload,
+		RetryCount:    job.RetryCount,
+		LastError:     job.LastError,
+		NextAttemptAt: job.NextAttemptAt,
+		FailedAt:      job.FailedAt,
+		CreatedAt:     job.CreatedAt,
+	}
+}
+
+// ToJob 转换为任务
+func (r *TaskRecord) ToJob() *Job {
+	return &Job{
+		ID:            r.ID,
+		Type:          r.Type,
+		OwnerID:       r.OwnerID,
+		ImageID:       r.ImageID,
+		Payload:       r.Payload,
+		CreatedAt:     r.CreatedAt,
+		RetryCount:    r.RetryCount,
+		Status:        r.Status,
+		LastError:     r.LastError,
+		NextAttemptAt: r.NextAttemptAt,
+		FailedAt:      r.FailedAt,
+	}
+}
@@ -11,6 +11,7 @@ type User struct {
 	Password  string    `json:"-" gorm:"size:100;not null"` // 密码不返回给前端
 	Email     string    `json:"email" gorm:"uniqueIndex;size:100;not null"`
 	Status    string    `json:"status" gorm:"size:20;default:'active'"`
+	Roles     []Role    `json:"-" gorm:"many2many:user_roles"`
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
@@ -21,6 +22,7 @@ type UserResponse struct {
 	Username  string    `json:"username"`
 	Email     string    `json:"email"`
 	Status    string    `json:"status"`
+	Roles     []string  `json:"roles"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -55,8 +57,15 @@ type LoginRequest struct {
 
 // LoginResponse 登录响应
 type LoginResponse struct {
-	Token string       `json:"token"`
-	User  UserResponse `json:"user"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refresh_token"`
+	ExpiresIn    int64        `json:"expires_in"` // 访问令牌有效期（秒）
+	User         UserResponse `json:"user"`
+}
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 // ToResponse 转换为响应结构体
@@ -66,8 +75,33 @@ func (u *User) ToResponse() UserResponse {
 		Username:  u.Username,
 		Email:     u.Email,
 		Status:    u.Status,
+		Roles:     u.RoleNames(),
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
 	}
 }
+
+// RoleNames 获取用户的角色名
+func (u *User) RoleNames() []string {
+	names := make([]string, len(u.Roles))
+	for i, role := range u.Roles {
+		names[i] = role.Name
+	}
+	return names
+}
+
+// PermissionCodes 获取用户通过角色拥有的全部权限码（去重）
+func (u *User) PermissionCodes() []string {
+	seen := make(map[string]bool)
+	var codes []string
+	for _, role := range u.Roles {
+		for _, code := range role.PermissionCodes() {
+			if !seen[code] {
+				seen[code] = true
+				codes = append(codes, code)
+			}
+		}
+	}
+	return codes
+}
  
\ No newline at end of file
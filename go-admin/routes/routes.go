@@ -1,15 +1,19 @@
 package routes
 
 import (
+	"net/http"
+
+	"go-admin/events"
 	"go-admin/handlers"
 	"go-admin/middleware"
+	"go-admin/models"
 	"go-admin/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SetupRoutes 设置路由
-func SetupRoutes(r *gin.Engine, jwtManager *utils.JWTManager, userService handlers.UserService, imageService handlers.ImageService) {
+func SetupRoutes(r *gin.Engine, jwtManager *utils.JWTManager, userService handlers.UserService, imageService handlers.ImageService, taskService handlers.TaskService, eventHub *events.Hub, cleanupLeader *utils.LeaderElector, readiness *utils.Readiness) {
 	// API v1 路由组
 	apiV1 := r.Group("/api/v1")
 	{
@@ -17,13 +21,14 @@ func SetupRoutes(r *gin.Engine, jwtManager *utils.JWTManager, userService handle
 		public := apiV1.Group("")
 		{
 			// 健康检查
-			public.GET("/health", healthCheck)
+			public.GET("/health", healthCheck(cleanupLeader, readiness))
 
 			// 认证相关路由
 			authHandler := handlers.NewAuthHandler(jwtManager, userService)
 			auth := public.Group("/auth")
 			{
 				auth.POST("/login", authHandler.Login)
+				auth.POST("/refresh", authHandler.Refresh)
 			}
 
 			// 公开的图片访问路由（不需要认证）
@@ -31,46 +36,89 @@ func SetupRoutes(r *gin.Engine, jwtManager *utils.JWTManager, userService handle
 			public.GET("/images/code/:code", imageHandler.GetImageByCode)
 			public.GET("/images/file/:code", imageHandler.ServeImage)
 			public.GET("/images/random", imageHandler.GetRandomImage) // 随机获取图片
-			public.GET("/images/task/:taskId", imageHandler.GetTaskStatus) // 查询任务状态
 		}
 
+		// 实时事件（EventSource无法设置请求头，通过一次性票据认证）
+		eventHandler := handlers.NewEventHandler(eventHub, jwtManager)
+		apiV1.GET("/events", middleware.StreamTicketMiddleware(jwtManager, "ticket"), eventHandler.Stream)
+
 		// 需要认证的路由
 		protected := apiV1.Group("")
 		protected.Use(middleware.AuthMiddleware(jwtManager))
 		{
+			authHandler := handlers.NewAuthHandler(jwtManager, userService)
+
 			// 用户相关路由
 			userHandler := handlers.NewUserHandler(userService)
 			users := protected.Group("/users")
 			{
-				users.GET("", userHandler.GetUsers)
-				users.GET("/:id", userHandler.GetUser)
-				users.POST("", userHandler.CreateUser)
-				users.PUT("/:id", userHandler.UpdateUser)
-				users.DELETE("/:id", userHandler.DeleteUser)
+				users.GET("", middleware.RequirePermission(models.PermUsersRead), userHandler.GetUsers)
+				users.GET("/:id", middleware.RequirePermission(models.PermUsersRead), userHandler.GetUser)
+				users.POST("", middleware.RequirePermission(models.PermUsersWrite), userHandler.CreateUser)
+				users.PUT("/:id", middleware.RequirePermission(models.PermUsersWrite), userHandler.UpdateUser)
+				users.DELETE("/:id", middleware.RequirePermission(models.PermUsersDelete), userHandler.DeleteUser)
+				users.DELETE("/:id/sessions", middleware.RequirePermission(models.PermUsersSessions), authHandler.RevokeUserSessions) // 吊销用户全部会话
+				users.PUT("/:id/roles", middleware.RequirePermission(models.PermRolesManage), userHandler.AssignRoles)                // 设置用户角色
 			}
 
+			// 角色列表
+			protected.GET("/roles", middleware.RequirePermission(models.PermRolesManage), userHandler.GetRoles)
+
 			// 获取当前用户信息
-			authHandler := handlers.NewAuthHandler(jwtManager, userService)
 			protected.GET("/auth/profile", authHandler.GetProfile)
 			protected.PUT("/auth/profile", userHandler.UpdateProfile)
+			protected.POST("/auth/logout", authHandler.Logout)
+
+			// 签发实时事件连接票据
+			protected.POST("/events/ticket", eventHandler.Ticket)
 
 			// 图片管理路由
 			imageHandler := handlers.NewImageHandler(imageService)
 			images := protected.Group("/images")
 			{
-				images.POST("/upload", imageHandler.UploadImage)
-				images.GET("", imageHandler.GetImages)
-				images.GET("/:id", imageHandler.GetImage)
-				images.DELETE("/:id", imageHandler.DeleteImage)
+				images.POST("/upload", middleware.RequirePermission(models.PermImagesUpload), imageHandler.UploadImage)
+				images.GET("", middleware.RequirePermission(models.PermImagesRead), imageHandler.GetImages)
+				images.GET("/:id", middleware.RequirePermission(models.PermImagesRead), imageHandler.GetImage)
+				images.DELETE("/:id", middleware.RequirePermission(models.PermImagesDelete), imageHandler.DeleteImage)
+			}
+
+			// 后台任务路由，非管理员只能访问自己的任务
+			taskHandler := handlers.NewTaskHandler(taskService)
+			images.GET("/task/:taskId", middleware.RequirePermission(models.PermImagesRead), taskHandler.GetTask) // 兼容旧路径，同 /tasks/:taskId
+			tasks := protected.Group("/tasks")
+			{
+				tasks.GET("", middleware.RequirePermission(models.PermImagesRead), taskHandler.GetTasks)                     // 任务列表
+				tasks.GET("/:taskId", middleware.RequirePermission(models.PermImagesRead), taskHandler.GetTask)              // 任务详情
+				tasks.POST("/:taskId/cancel", middleware.RequirePermission(models.PermImagesDelete), taskHandler.CancelTask) // 取消任务
+			}
+
+			// 后台任务管理路由
+			taskAdmin := protected.Group("/tasks")
+			taskAdmin.Use(middleware.RequirePermission(models.PermTasksManage))
+			{
+				taskAdmin.GET("/metrics", taskHandler.GetMetrics)                    // 队列指标
+				taskAdmin.GET("/dead", taskHandler.GetDeadLetters)                   // 死信任务列表
+				taskAdmin.DELETE("/dead", taskHandler.PurgeDeadLetters)              // 清空死信队列
+				taskAdmin.POST("/dead/replay", taskHandler.ReplayAllDeadLetters)     // 全部重新执行
+				taskAdmin.GET("/dead/:taskId", taskHandler.GetDeadLetter)            // 死信任务详情
+				taskAdmin.DELETE("/dead/:taskId", taskHandler.DeleteDeadLetter)      // 丢弃死信任务
+				taskAdmin.POST("/dead/:taskId/replay", taskHandler.ReplayDeadLetter) // 重新执行死信任务
 			}
 		}
 	}
 }
 
-// healthCheck 健康检查
-func healthCheck(c *gin.Context) {
-	utils.Success(c, gin.H{
-		"status":  "ok",
-		"message": "Go Admin API v1 is running",
-	})
+// healthCheck 健康检查，同时返回本实例的清理任务领导权状态，停止过程中返回503
+func healthCheck(cleanupLeader *utils.LeaderElector, readiness *utils.Readiness) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !readiness.Ready() {
+			utils.Error(c, http.StatusServiceUnavailable, "Go Admin API v1 is shutting down")
+			return
+		}
+		utils.Success(c, gin.H{
+			"status":  "ok",
+			"message": "Go Admin API v1 is running",
+			"leader":  cleanupLeader.Status(),
+		})
+	}
 }
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+)
+
+// LocalBackend 本地文件系统存储
+type LocalBackend struct {
+	root string
+}
+
+// NewLocalBackend 创建本地存储，目录不存在时自动创建
+func NewLocalBackend(root string) (*LocalBackend, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+	return &LocalBackend{root: root}, nil
+}
+
+// Put 写入对象，先写临时文件再重命名，避免读到写了一半的文件
+func (b *LocalBackend) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get 顺序读取整个对象
+func (b *LocalBackend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return b.Open(ctx, key)
+}
+
+// Open 打开对象用于随机读取
+func (b *LocalBackend) Open(ctx context.Context, key string) (Object, error) {
+	path, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, mapLocalError(err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &localObject{File: file, info: b.info(key, stat)}, nil
+}
+
+// Stat 获取对象元信息
+func (b *LocalBackend) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	path, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, mapLocalError(err)
+	}
+
+	info := b.info(key, stat)
+	return &info, nil
+}
+
+// Delete 删除对象
+func (b *LocalBackend) Delete(ctx context.Context, key string) error {
+	path, err := b.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// path 将对象键转换为文件路径，禁止越出根目录
+func (b *LocalBackend) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + filepath.FromSlash(key))
+	if cleaned == string(filepath.Separator) {
+		return "", fmt.Errorf("invalid object key: %s", key)
+	}
+	return filepath.Join(b.root, cleaned), nil
+}
+
+// info 构造对象元信息
+func (b *LocalBackend) info(key string, stat fs.FileInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:         key,
+		Size:        stat.Size(),
+		ContentType: mime.TypeByExtension(filepath.Ext(key)),
+		ModTime:     stat.ModTime(),
+	}
+}
+
+// localObject 本地文件对象
+type localObject struct {
+	*os.File
+	info ObjectInfo
+}
+
+// Info 获取对象元信息
+func (o *localObject) Info() ObjectInfo {
+	return o.info
+}
+
+// mapLocalError 转换文件不存在错误
+func mapLocalError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
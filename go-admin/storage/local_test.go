@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalBackend(t *testing.T) {
+	backend, err := NewLocalBackend(filepath.Join(t.TempDir(), "images"))
+	if err != nil {
+		t.Fatalf("NewLocalBackend: %v", err)
+	}
+	testBackend(t, backend)
+}
+
+func TestLocalBackendStaysInsideRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "images")
+	backend, err := NewLocalBackend(root)
+	if err != nil {
+		t.Fatalf("NewLocalBackend: %v", err)
+	}
+
+	data := []byte("data")
+	if err := backend.Put(context.Background(), "../escape.png", bytes.NewReader(data), int64(len(data)), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.png")); !os.IsNotExist(err) {
+		t.Fatalf("object was written outside the storage root")
+	}
+	if _, err := os.Stat(filepath.Join(root, "escape.png")); err != nil {
+		t.Fatalf("object not written inside the storage root: %v", err)
+	}
+
+	if err := backend.Put(context.Background(), "/", bytes.NewReader(data), int64(len(data)), "image/png"); err == nil {
+		t.Fatalf("Put with an empty key succeeded")
+	}
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go-admin/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Backend S3兼容对象存储（AWS S3、MinIO等）
+type S3Backend struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3Backend 创建S3存储，桶不存在时自动创建
+func NewS3Backend(cfg *config.S3Config) (*S3Backend, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client: %v", err)
+	}
+
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check s3 bucket: %v", err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region}); err != nil {
+			return nil, fmt.Errorf("failed to create s3 bucket: %v", err)
+		}
+	}
+
+	return &S3Backend{client: client, bucket: cfg.Bucket}, nil
+}
+
+// Put 写入对象
+func (b *S3Backend) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := b.client.PutObject(ctx, b.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	return err
+}
+
+// Get 顺序读取整个对象
+func (b *S3Backend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return b.Open(ctx, key)
+}
+
+// Open 打开对象，minio.Object本身支持Seek，读取时按需发起Range请求
+func (b *S3Backend) Open(ctx context.Context, key string) (Object, error) {
+	object, err := b.client.GetObject(ctx, b.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, mapS3Error(err)
+	}
+
+	stat, err := object.Stat()
+	if err != nil {
+		object.Close()
+		return nil, mapS3Error(err)
+	}
+
+	return &s3Object{Object: object, info: toObjectInfo(stat)}, nil
+}
+
+// Stat 获取对象元信息
+func (b *S3Backend) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	stat, err := b.client.StatObject(ctx, b.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, mapS3Error(err)
+	}
+
+	info := toObjectInfo(stat)
+	return &info, nil
+}
+
+// Delete 删除对象，S3删除不存在的对象同样返回成功
+func (b *S3Backend) Delete(ctx context.Context, key string) error {
+	return b.client.RemoveObject(ctx, b.bucket, key, minio.RemoveObjectOptions{})
+}
+
+// s3Object S3对象
+type s3Object struct {
+	*minio.Object
+	info ObjectInfo
+}
+
+// Info 获取对象元信息
+func (o *s3Object) Info() ObjectInfo {
+	return o.info
+}
+
+// toObjectInfo 转换对象元信息
+func toObjectInfo(stat minio.ObjectInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:         stat.Key,
+		Size:        stat.Size,
+		ContentType: stat.ContentType,
+		ModTime:     stat.LastModified,
+	}
+}
+
+// mapS3Error 转换对象不存在错误
+func mapS3Error(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-admin/config"
+)
+
+// fakeS3 内存中的S3兼容服务，只实现S3Backend用到的桶和对象接口（路径风格请求）
+type fakeS3 struct {
+	mu      sync.Mutex
+	buckets map[string]map[string]fakeObject
+}
+
+// fakeObject 已保存的对象
+type fakeObject struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+// s3Error S3错误响应
+type s3Error struct {
+	XMLName    xml.Name `xml:"Error"`
+	Code       string   `xml:"Code"`
+	Message    string   `xml:"Message"`
+	BucketName string   `xml:"BucketName,omitempty"`
+	Key        string   `xml:"Key,omitempty"`
+}
+
+// newFakeS3 启动内存S3服务，测试结束时关闭
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	t.Helper()
+	fake := &fakeS3{buckets: make(map[string]map[string]fakeObject)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket == "" {
+		http.Error(w, "bucket required", http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	objects, exists := f.buckets[bucket]
+	if key == "" {
+		switch r.Method {
+		case http.MethodHead:
+			if !exists {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case http.MethodPut:
+			if !exists {
+				f.buckets[bucket] = make(map[string]fakeObject)
+			}
+		default:
+			http.Error(w, "unsupported bucket operation", http.StatusNotImplemented)
+		}
+		return
+	}
+	if !exists {
+		writeS3Error(w, r, http.StatusNotFound, s3Error{Code: "NoSuchBucket", Message: "bucket does not exist", BucketName: bucket})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := readS3Body(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		objects[key] = fakeObject{data: data, contentType: r.Header.Get("Content-Type"), modTime: time.Now().UTC()}
+		w.Header().Set("ETag", etag(data))
+	case http.MethodGet, http.MethodHead:
+		object, ok := objects[key]
+		if !ok {
+			writeS3Error(w, r, http.StatusNotFound, s3Error{Code: "NoSuchKey", Message: "key does not exist", BucketName: bucket, Key: key})
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Header().Set("ETag", etag(object.data))
+		http.ServeContent(w, r, key, object.modTime, bytes.NewReader(object.data))
+	case http.MethodDelete:
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unsupported object operation", http.StatusNotImplemented)
+	}
+}
+
+// writeS3Error 返回XML错误，HEAD请求没有响应体
+func writeS3Error(w http.ResponseWriter, r *http.Request, status int, s3Err s3Error) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		xml.NewEncoder(w).Encode(s3Err)
+	}
+}
+
+// readS3Body 读取上传内容，流式签名时按 aws-chunked 格式解码
+func readS3Body(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var data bytes.Buffer
+	reader := bufio.NewReader(r.Body)
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk header: %v", err)
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(header), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk size %q: %v", sizeHex, err)
+		}
+		if size == 0 {
+			return data.Bytes(), nil
+		}
+		if _, err := io.CopyN(&data, reader, size); err != nil {
+			return nil, fmt.Errorf("short chunk: %v", err)
+		}
+		if _, err := reader.Discard(2); err != nil { // 块末尾的 \r\n
+			return nil, fmt.Errorf("invalid chunk trailer: %v", err)
+		}
+	}
+}
+
+// etag 对象内容的MD5
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// newTestS3Backend 创建连接内存S3服务的存储
+func newTestS3Backend(t *testing.T, server *httptest.Server) *S3Backend {
+	t.Helper()
+	backend, err := NewS3Backend(&config.S3Config{
+		Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+		AccessKey: "test-access-key",
+		SecretKey: "test-secret-key",
+		Bucket:    "images",
+		Region:    "us-east-1",
+	})
+	if err != nil {
+		t.Fatalf("NewS3Backend: %v", err)
+	}
+	return backend
+}
+
+func TestS3Backend(t *testing.T) {
+	fake, server := newFakeS3(t)
+	backend := newTestS3Backend(t, server)
+
+	// 桶不存在时自动创建
+	if _, ok := fake.buckets["images"]; !ok {
+		t.Fatalf("bucket was not created")
+	}
+	testBackend(t, backend)
+
+	// 桶已存在时直接使用
+	newTestS3Backend(t, server)
+}
+
+func TestS3BackendLargeObject(t *testing.T) {
+	_, server := newFakeS3(t)
+	backend := newTestS3Backend(t, server)
+
+	// 大于一个签名块（64KiB）的内容分多块上传
+	data := bytes.Repeat([]byte("0123456789abcdef"), 20000)
+	if err := backend.Put(context.Background(), "images/large.png", bytes.NewReader(data), int64(len(data)), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	reader, err := backend.Get(context.Background(), "images/large.png")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer reader.Close()
+	got, err := io.ReadAll(reader)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Get read %d bytes, %v, want %d bytes", len(got), err, len(data))
+	}
+}
+
+// TestS3BackendMinIO 设置 S3_TEST_ENDPOINT 等环境变量时对真实的MinIO运行相同的检查，例如：
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	S3_TEST_ENDPOINT=localhost:9000 S3_TEST_ACCESS_KEY=minioadmin S3_TEST_SECRET_KEY=minioadmin go test ./storage/
+func TestS3BackendMinIO(t *testing.T) {
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_TEST_ENDPOINT not set")
+	}
+	backend, err := NewS3Backend(&config.S3Config{
+		Endpoint:  endpoint,
+		AccessKey: os.Getenv("S3_TEST_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_TEST_SECRET_KEY"),
+		Bucket:    fmt.Sprintf("go-admin-test-%d", time.Now().UnixNano()),
+		UseSSL:    os.Getenv("S3_TEST_USE_SSL") == "true",
+	})
+	if err != nil {
+		t.Fatalf("NewS3Backend: %v", err)
+	}
+	testBackend(t, backend)
+}
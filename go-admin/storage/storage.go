@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go-admin/config"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("object not found")
+
+// ObjectInfo 对象元信息
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Object 支持随机读取的对象，可直接用于http.ServeContent处理Range请求
+type Object interface {
+	io.ReadSeekCloser
+	Info() ObjectInfo
+}
+
+// Backend 图片文件存储后端接口
+type Backend interface {
+	// Put 写入对象，size未知时传-1
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 顺序读取整个对象
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Open 打开对象用于随机读取（Range请求）
+	Open(ctx context.Context, key string) (Object, error)
+	// Stat 获取对象元信息
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+}
+
+// New 根据配置创建存储后端
+func New(cfg *config.StorageConfig) (Backend, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocalBackend(cfg.LocalDir)
+	case "s3":
+		return NewS3Backend(&cfg.S3)
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", cfg.Driver)
+	}
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// testBackend 各存储实现共同遵守的行为
+func testBackend(t *testing.T, backend Backend) {
+	t.Helper()
+	ctx := context.Background()
+	const key = "images/ab/cdef.png"
+	data := []byte("not really a png, but storage does not care")
+
+	if _, err := backend.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Stat(missing) = %v, want ErrNotFound", err)
+	}
+	if _, err := backend.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open(missing) = %v, want ErrNotFound", err)
+	}
+
+	if err := backend.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	info, err := backend.Stat(ctx, key)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size != int64(len(data)) || info.ContentType != "image/png" {
+		t.Fatalf("Stat = %+v, want size %d and image/png", info, len(data))
+	}
+
+	reader, err := backend.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Get read %q, %v, want %q", got, err, data)
+	}
+
+	// 随机读取，HTTP服务按Range返回部分内容依赖此行为
+	object, err := backend.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer object.Close()
+	if object.Info().Size != int64(len(data)) {
+		t.Fatalf("Info().Size = %d, want %d", object.Info().Size, len(data))
+	}
+	if _, err := object.Seek(4, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	got, err = io.ReadAll(object)
+	if err != nil || !bytes.Equal(got, data[4:]) {
+		t.Fatalf("read after Seek = %q, %v, want %q", got, err, data[4:])
+	}
+
+	if err := backend.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := backend.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Stat after Delete = %v, want ErrNotFound", err)
+	}
+	// 删除不存在的对象不是错误
+	if err := backend.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete(missing): %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+// Package testutil 测试使用的临时SQLite数据库和内存Redis，不依赖外部服务
+package testutil
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go-admin/config"
+	"go-admin/database"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm/logger"
+)
+
+// Config 默认配置，数据库为测试临时目录中的SQLite文件，本地存储目录同样位于临时目录
+func Config(t testing.TB) *config.Config {
+	t.Helper()
+	cfg, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	dir := t.TempDir()
+	cfg.Database = config.DatabaseConfig{Driver: config.DriverSQLite, Path: filepath.Join(dir, "test.db")}
+	cfg.Storage.Driver = "local"
+	cfg.Storage.LocalDir = filepath.Join(dir, "uploads")
+	return cfg
+}
+
+// NewDB 连接 cfg 指定的数据库并执行全部迁移，测试结束时关闭连接
+func NewDB(t testing.TB, cfg *config.Config) {
+	t.Helper()
+	if err := database.Connect(&cfg.Database); err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.DB.Logger = logger.Discard
+	t.Cleanup(func() { database.Close() })
+
+	if _, err := database.MigrateUp(cfg); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+}
+
+// NewRedis 启动内存Redis并设置 config.RedisClient，测试结束时关闭
+func NewRedis(t testing.TB) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	config.RedisClient = client
+	return server, client
+}
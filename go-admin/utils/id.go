@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// crockfordAlphabet ULID使用的Crockford Base32字母表，不含容易混淆的I、L、O、U
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGenerator 同一毫秒内生成的ULID在随机部分上递增，保证单进程内严格有序
+type ulidGenerator struct {
+	mu       sync.Mutex
+	lastTime uint64
+	lastRand [10]byte
+}
+
+var defaultULID = &ulidGenerator{}
+
+// NewULID 生成ULID：48位毫秒时间戳加80位随机数，26个字符，按字典序排序即按生成时间排序
+func NewULID() string {
+	return defaultULID.next(time.Now())
+}
+
+// next 生成指定时间的ULID
+func (g *ulidGenerator) next(now time.Time) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// 同一毫秒内或时钟回拨时递增随机部分，保持有序
+	ms := uint64(now.UnixMilli())
+	if ms > g.lastTime {
+		g.lastTime = ms
+		g.fillRandom()
+	} else if !incrementBytes(g.lastRand[:]) {
+		// 随机部分溢出时推进到下一毫秒
+		g.lastTime++
+		g.fillRandom()
+	}
+
+	var timestamp [8]byte
+	binary.BigEndian.PutUint64(timestamp[:], g.lastTime)
+	var id [16]byte
+	copy(id[:6], timestamp[2:])
+	copy(id[6:], g.lastRand[:])
+	return encodeCrockford(id)
+}
+
+// fillRandom 重新生成随机部分
+func (g *ulidGenerator) fillRandom() {
+	if _, err := rand.Read(g.lastRand[:]); err != nil {
+		panic("utils: crypto/rand unavailable: " + err.Error())
+	}
+}
+
+// incrementBytes 将大端字节序的整数加一，溢出时返回false
+func incrementBytes(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeCrockford 将128位数据编码为26个Crockford Base32字符
+func encodeCrockford(id [16]byte) string {
+	n := new(big.Int).SetBytes(id[:])
+	base := big.NewInt(32)
+	mod := new(big.Int)
+
+	out := make([]byte, 26)
+	for i := len(out) - 1; i >= 0; i-- {
+		n.DivMod(n, base, mod)
+		out[i] = crockfordAlphabet[mod.Int64()]
+	}
+	return string(out)
+}
+
+// RandomString 使用crypto/rand从字母表中均匀地随机选取字符
+func RandomString(length int, alphabet string) (string, error) {
+	if length < 1 {
+		return "", errors.New("length must be positive")
+	}
+	if len(alphabet) < 2 {
+		return "", errors.New("alphabet must contain at least 2 characters")
+	}
+
+	size := big.NewInt(int64(len(alphabet)))
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		b[i] = alphabet[n.Int64()]
+	}
+	return string(b), nil
+}
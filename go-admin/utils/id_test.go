@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestULIDSortsByTime(t *testing.T) {
+	g := &ulidGenerator{}
+	base := time.UnixMilli(1700000000000)
+
+	var ids []string
+	for i := 0; i < 50; i++ {
+		// 每个时间点生成多个，同一毫秒内也必须递增
+		for j := 0; j < 3; j++ {
+			ids = append(ids, g.next(base.Add(time.Duration(i)*time.Millisecond)))
+		}
+	}
+	// 时钟回拨时仍然递增
+	ids = append(ids, g.next(base))
+
+	for i, id := range ids {
+		if len(id) != 26 {
+			t.Fatalf("ULID %q has length %d, want 26", id, len(id))
+		}
+		if strings.Trim(id, crockfordAlphabet) != "" {
+			t.Fatalf("ULID %q contains characters outside the Crockford alphabet", id)
+		}
+		if i > 0 && id <= ids[i-1] {
+			t.Fatalf("ULID %d %q does not sort after %q", i, id, ids[i-1])
+		}
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Fatal("ULIDs are not sorted")
+	}
+
+	// 时间戳部分按毫秒编码
+	later := (&ulidGenerator{}).next(base.Add(time.Hour))
+	if later[:10] <= ids[len(ids)-1][:10] {
+		t.Fatalf("timestamp of %q does not sort after %q", later, ids[len(ids)-1])
+	}
+}
+
+func TestULIDRandomOverflow(t *testing.T) {
+	g := &ulidGenerator{}
+	now := time.UnixMilli(1700000000000)
+	first := g.next(now)
+
+	// 同一毫秒内随机部分耗尽时推进到下一毫秒
+	for i := range g.lastRand {
+		g.lastRand[i] = 0xFF
+	}
+	lastTime := g.lastTime
+	second := g.next(now)
+	if g.lastTime != lastTime+1 {
+		t.Fatalf("lastTime = %d, want %d", g.lastTime, lastTime+1)
+	}
+	if second <= first {
+		t.Fatalf("ULID %q does not sort after %q", second, first)
+	}
+}
+
+func TestRandomString(t *testing.T) {
+	tests := []struct {
+		length   int
+		alphabet string
+	}{
+		{8, "0123456789abcdefghijklmnopqrstuvwxyz"},
+		{4, "ab"},
+		{32, "ABCDEFGHJKMNPQRSTVWXYZ"},
+	}
+	for _, tt := range tests {
+		seen := make(map[rune]bool)
+		for i := 0; i < 200; i++ {
+			s, err := RandomString(tt.length, tt.alphabet)
+			if err != nil {
+				t.Fatalf("RandomString(%d, %q): %v", tt.length, tt.alphabet, err)
+			}
+			if len(s) != tt.length {
+				t.Fatalf("RandomString(%d) = %q", tt.length, s)
+			}
+			for _, c := range s {
+				if !strings.ContainsRune(tt.alphabet, c) {
+					t.Fatalf("RandomString = %q, %q is not in %q", s, c, tt.alphabet)
+				}
+				seen[c] = true
+			}
+		}
+		// 生成足够多次后字母表中的每个字符都会出现
+		if len(seen) != len(tt.alphabet) {
+			t.Errorf("alphabet %q: only %d characters used", tt.alphabet, len(seen))
+		}
+	}
+
+	for _, tt := range []struct {
+		length   int
+		alphabet string
+	}{{0, "ab"}, {-1, "ab"}, {8, "a"}, {8, ""}} {
+		if _, err := RandomString(tt.length, tt.alphabet); err == nil {
+			t.Errorf("RandomString(%d, %q) succeeded", tt.length, tt.alphabet)
+		}
+	}
+}
@@ -1,62 +1,365 @@
 package utils
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// 会话相关的Redis键
+const (
+	refreshTokenKeyPrefix = "auth:refresh:"         // 刷新令牌（按哈希存储）
+	sessionKeyPrefix      = "auth:session:"         // 会话（刷新令牌家族）
+	userSessionsKeyFormat = "auth:user:%d:sessions" // 用户的全部会话
+	denylistKeyPrefix     = "auth:denylist:"        // 已吊销的访问令牌jti
+	streamTicketKeyPrefix = "auth:ticket:"          // 实时事件连接票据（按哈希存储）
+)
+
+// StreamTicketTTL 实时事件连接票据的有效期，票据只能使用一次
+const StreamTicketTTL = 30 * time.Second
+
+var (
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
 )
 
 // Claims JWT声明
 type Claims struct {
-	UserID   int    `json:"user_id"`
-	Username string `json:"username"`
+	UserID      int      `json:"user_id"`
+	Username    string   `json:"username"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	SessionID   string   `json:"sid"`
 	jwt.RegisteredClaims
 }
 
+// HasRole 判断是否拥有角色
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission 判断是否拥有权限
+func (c *Claims) HasPermission(permission string) bool {
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Principal 签发令牌时的用户身份
+type Principal struct {
+	UserID      int
+	Username    string
+	Roles       []string
+	Permissions []string
+}
+
+// PrincipalLoader 刷新令牌时重新加载用户身份，保证角色变更及时生效
+type PrincipalLoader func(userID int) (*Principal, error)
+
+// TokenPair 访问令牌与刷新令牌
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // 访问令牌有效期（秒）
+}
+
 // JWTManager JWT管理器
 type JWTManager struct {
-	secret     string
-	expireTime time.Duration
+	secret            string
+	accessExpireTime  time.Duration
+	refreshExpireTime time.Duration
+	redisClient       *redis.Client
 }
 
 // NewJWTManager 创建JWT管理器
-func NewJWTManager(secret string, expireTime time.Duration) *JWTManager {
+func NewJWTManager(secret string, accessExpireTime, refreshExpireTime time.Duration, redisClient *redis.Client) *JWTManager {
 	return &JWTManager{
-		secret:     secret,
-		expireTime: expireTime,
+		secret:            secret,
+		accessExpireTime:  accessExpireTime,
+		refreshExpireTime: refreshExpireTime,
+		redisClient:       redisClient,
 	}
 }
 
-// GenerateToken 生成JWT token
-func (j *JWTManager) GenerateToken(userID int, username string) (string, error) {
-	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.expireTime)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
+// GenerateTokenPair 登录时创建新会话并签发令牌对
+func (j *JWTManager) GenerateTokenPair(principal *Principal) (*TokenPair, error) {
+	ctx := context.Background()
+	sessionID := uuid.New().String()
+
+	pipe := j.redisClient.TxPipeline()
+	pipe.Set(ctx, sessionKeyPrefix+sessionID, principal.UserID, j.refreshExpireTime)
+	userSessionsKey := fmt.Sprintf(userSessionsKeyFormat, principal.UserID)
+	pipe.SAdd(ctx, userSessionsKey, sessionID)
+	pipe.Expire(ctx, userSessionsKey, j.refreshExpireTime)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.secret))
+	return j.issueTokenPair(ctx, principal, sessionID)
+}
+
+// Refresh 使用刷新令牌换取新的令牌对，旧刷新令牌随即失效
+func (j *JWTManager) Refresh(refreshToken string, load PrincipalLoader) (*TokenPair, error) {
+	ctx := context.Background()
+	key := refreshTokenKeyPrefix + hashToken(refreshToken)
+
+	record, err := j.redisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(record) == 0 {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	userID, err := strconv.Atoi(record["user_id"])
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	sessionID := record["session_id"]
+
+	active, err := j.redisClient.Exists(ctx, sessionKeyPrefix+sessionID).Result()
+	if err != nil {
+		return nil, err
+	}
+	if active == 0 {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// 原子地标记为已使用，重复使用说明令牌可能已泄露，吊销整个会话
+	used, err := j.redisClient.HIncrBy(ctx, key, "used", 1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if used > 1 {
+		if err := j.RevokeSession(sessionID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	principal, err := load(userID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// 续期会话
+	pipe := j.redisClient.TxPipeline()
+	pipe.Expire(ctx, sessionKeyPrefix+sessionID, j.refreshExpireTime)
+	pipe.Expire(ctx, fmt.Sprintf(userSessionsKeyFormat, userID), j.refreshExpireTime)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return j.issueTokenPair(ctx, principal, sessionID)
 }
 
 // ValidateToken 验证JWT token
 func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(j.secret), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid || claims.ID == "" || claims.SessionID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	if err := j.CheckSession(claims); err != nil {
+		return nil, err
 	}
+	return claims, nil
+}
+
+// CheckSession 检查令牌是否被吊销以及会话是否仍然有效，用于长连接期间的定期检查
+func (j *JWTManager) CheckSession(claims *Claims) error {
+	ctx := context.Background()
+	pipe := j.redisClient.Pipeline()
+	denied := pipe.Exists(ctx, denylistKeyPrefix+claims.ID)
+	session := pipe.Exists(ctx, sessionKeyPrefix+claims.SessionID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	if denied.Val() > 0 || session.Val() == 0 {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// IssueStreamTicket 为已认证的请求签发一次性的实时事件连接票据。
+// 浏览器的EventSource无法设置请求头，用短期票据代替访问令牌放在查询参数中
+func (j *JWTManager) IssueStreamTicket(claims *Claims) (string, error) {
+	ticket, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	if err := j.redisClient.Set(context.Background(), streamTicketKeyPrefix+hashToken(ticket), data, StreamTicketTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store stream ticket: %v", err)
+	}
+	return ticket, nil
+}
+
+// RedeemStreamTicket 兑换并作废票据，返回签发票据时访问令牌的声明
+func (j *JWTManager) RedeemStreamTicket(ticket string) (*Claims, error) {
+	data, err := j.redisClient.GetDel(context.Background(), streamTicketKeyPrefix+hashToken(ticket)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.ExpiresAt == nil || !claims.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidToken
+	}
+	if err := j.CheckSession(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// RevokeToken 将访问令牌加入黑名单直至其自然过期
+func (j *JWTManager) RevokeToken(claims *Claims) error {
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	return j.redisClient.Set(context.Background(), denylistKeyPrefix+claims.ID, 1, ttl).Err()
+}
+
+// RevokeSession 吊销会话，该会话下的访问令牌与刷新令牌全部失效
+func (j *JWTManager) RevokeSession(sessionID string) error {
+	ctx := context.Background()
+	userID, err := j.redisClient.Get(ctx, sessionKeyPrefix+sessionID).Int()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	pipe := j.redisClient.TxPipeline()
+	pipe.Del(ctx, sessionKeyPrefix+sessionID)
+	pipe.SRem(ctx, fmt.Sprintf(userSessionsKeyFormat, userID), sessionID)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// RevokeAllSessions 吊销用户的全部会话
+func (j *JWTManager) RevokeAllSessions(userID int) error {
+	ctx := context.Background()
+	userSessionsKey := fmt.Sprintf(userSessionsKeyFormat, userID)
+
+	sessionIDs, err := j.redisClient.SMembers(ctx, userSessionsKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(sessionIDs)+1)
+	for _, sessionID := range sessionIDs {
+		keys = append(keys, sessionKeyPrefix+sessionID)
+	}
+	keys = append(keys, userSessionsKey)
+
+	return j.redisClient.Del(ctx, keys...).Err()
+}
+
+// issueTokenPair 在指定会话下签发访问令牌和刷新令牌
+func (j *JWTManager) issueTokenPair(ctx context.Context, principal *Principal, sessionID string) (*TokenPair, error) {
+	accessToken, err := j.generateAccessToken(principal, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	key := refreshTokenKeyPrefix + hashToken(refreshToken)
+	pipe := j.redisClient.TxPipeline()
+	pipe.HSet(ctx, key, map[string]interface{}{
+		"user_id":    principal.UserID,
+		"session_id": sessionID,
+		"used":       0,
+	})
+	pipe.Expire(ctx, key, j.refreshExpireTime)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %v", err)
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(j.accessExpireTime.Seconds()),
+	}, nil
+}
+
+// generateAccessToken 生成访问令牌
+func (j *JWTManager) generateAccessToken(principal *Principal, sessionID string) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID:      principal.UserID,
+		Username:    principal.Username,
+		Roles:       principal.Roles,
+		Permissions: principal.Permissions,
+		SessionID:   sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.accessExpireTime)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(j.secret))
+}
+
+// generateOpaqueToken 生成随机的不透明令牌
+func generateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
 
-	return nil, errors.New("invalid token")
+// hashToken Redis中只保存刷新令牌的哈希
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedis 启动内存Redis
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func newTestJWTManager(t *testing.T) *JWTManager {
+	t.Helper()
+	_, client := newTestRedis(t)
+	return NewJWTManager("test-secret", 15*time.Minute, time.Hour, client)
+}
+
+func TestRefreshRotatesAndDetectsReuse(t *testing.T) {
+	manager := newTestJWTManager(t)
+	principal := &Principal{UserID: 1, Username: "admin"}
+	load := func(userID int) (*Principal, error) { return principal, nil }
+
+	tokens, err := manager.GenerateTokenPair(principal)
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+
+	rotated, err := manager.Refresh(tokens.RefreshToken, load)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, err := manager.ValidateToken(rotated.AccessToken); err != nil {
+		t.Fatalf("ValidateToken(rotated): %v", err)
+	}
+
+	// 重复使用旧刷新令牌时吊销整个会话
+	if _, err := manager.Refresh(tokens.RefreshToken, load); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Refresh(reused) = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := manager.ValidateToken(rotated.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("ValidateToken after reuse = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := manager.Refresh(rotated.RefreshToken, load); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh(rotated) after reuse = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	manager := newTestJWTManager(t)
+	principal := &Principal{UserID: 7, Username: "user"}
+
+	first, err := manager.GenerateTokenPair(principal)
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+	second, err := manager.GenerateTokenPair(principal)
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+
+	if err := manager.RevokeAllSessions(principal.UserID); err != nil {
+		t.Fatalf("RevokeAllSessions: %v", err)
+	}
+	for _, tokens := range []*TokenPair{first, second} {
+		if _, err := manager.ValidateToken(tokens.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+			t.Errorf("ValidateToken after revoke = %v, want ErrTokenRevoked", err)
+		}
+	}
+}
+
+func TestRevokeTokenRevokesOnlyThatToken(t *testing.T) {
+	manager := newTestJWTManager(t)
+	principal := &Principal{UserID: 5, Username: "user"}
+
+	tokens, err := manager.GenerateTokenPair(principal)
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+	other, err := manager.GenerateTokenPair(principal)
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+	claims, err := manager.ValidateToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+
+	if err := manager.RevokeToken(claims); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if _, err := manager.ValidateToken(tokens.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("ValidateToken after RevokeToken = %v, want ErrTokenRevoked", err)
+	}
+	// 同一用户的其他会话不受影响
+	if _, err := manager.ValidateToken(other.AccessToken); err != nil {
+		t.Fatalf("ValidateToken(other session): %v", err)
+	}
+}
+
+func TestValidateTokenRejectsForeignSignature(t *testing.T) {
+	manager := newTestJWTManager(t)
+	_, client := newTestRedis(t)
+	foreign := NewJWTManager("another-secret", 15*time.Minute, time.Hour, client)
+
+	tokens, err := foreign.GenerateTokenPair(&Principal{UserID: 1, Username: "admin"})
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+	if _, err := manager.ValidateToken(tokens.AccessToken); err == nil {
+		t.Fatalf("ValidateToken accepted a token signed with another secret")
+	}
+}
+
+func TestStreamTicketIsSingleUse(t *testing.T) {
+	manager := newTestJWTManager(t)
+	tokens, err := manager.GenerateTokenPair(&Principal{UserID: 3, Username: "user"})
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+	claims, err := manager.ValidateToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+
+	ticket, err := manager.IssueStreamTicket(claims)
+	if err != nil {
+		t.Fatalf("IssueStreamTicket: %v", err)
+	}
+	redeemed, err := manager.RedeemStreamTicket(ticket)
+	if err != nil {
+		t.Fatalf("RedeemStreamTicket: %v", err)
+	}
+	if redeemed.UserID != claims.UserID || redeemed.SessionID != claims.SessionID || !redeemed.ExpiresAt.Equal(claims.ExpiresAt.Time) {
+		t.Fatalf("redeemed claims = %+v, want %+v", redeemed, claims)
+	}
+
+	if _, err := manager.RedeemStreamTicket(ticket); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("second RedeemStreamTicket = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestStreamTicketRejectedAfterRevoke(t *testing.T) {
+	manager := newTestJWTManager(t)
+	tokens, err := manager.GenerateTokenPair(&Principal{UserID: 3, Username: "user"})
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+	claims, err := manager.ValidateToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	ticket, err := manager.IssueStreamTicket(claims)
+	if err != nil {
+		t.Fatalf("IssueStreamTicket: %v", err)
+	}
+
+	if err := manager.RevokeSession(claims.SessionID); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if _, err := manager.RedeemStreamTicket(ticket); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("RedeemStreamTicket after revoke = %v, want ErrTokenRevoked", err)
+	}
+	if err := manager.CheckSession(claims); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("CheckSession after revoke = %v, want ErrTokenRevoked", err)
+	}
+}
@@ -0,0 +1,263 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// 领导选举相关的Redis键
+const (
+	leaderKeyPrefix      = "leader:"        // 当前领导者，值为 <实例ID>:<防护令牌>
+	leaderTokenKeySuffix = ":fencing_token" // 单调递增的防护令牌计数器
+)
+
+// acquireScript 领导权空缺时获取领导权，同时分配新的防护令牌。
+// 计数器小于令牌下限（Redis数据丢失后重置）时先提升到下限，新令牌总是大于存储层记录的令牌
+var acquireScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+local floor = tonumber(ARGV[3])
+if tonumber(redis.call('GET', KEYS[2]) or '0') < floor then
+	redis.call('SET', KEYS[2], floor)
+end
+local token = redis.call('INCR', KEYS[2])
+redis.call('SET', KEYS[1], ARGV[1] .. ':' .. token, 'PX', ARGV[2])
+return token
+`)
+
+// renewScript 仍持有领导权时续期
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript 仍持有领导权时主动释放
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// TokenFloor 返回存储层已接受的最大防护令牌，没有记录时返回0
+type TokenFloor func(ctx context.Context, name string) (int64, error)
+
+// LeaderStatus 领导权状态
+type LeaderStatus struct {
+	Name       string     `json:"name"`
+	InstanceID string     `json:"instance_id"`
+	IsLeader   bool       `json:"is_leader"`
+	Token      int64      `json:"fencing_token,omitempty"`
+	Since      *time.Time `json:"since,omitempty"`
+}
+
+// LeaderElector 基于Redis租约的领导选举。
+// 领导者按租约时长的三分之一周期续期，续期失败或超过租约时长未能续期时主动放弃领导权。
+// 每次获得领导权都会分配一个更大的防护令牌，写操作携带令牌，
+// 由存储层拒绝比已见过的令牌更小的请求，避免暂停后恢复的旧领导者继续写入
+type LeaderElector struct {
+	redisClient *redis.Client
+	name        string
+	instanceID  string
+	ttl         time.Duration
+	tokenFloor  TokenFloor
+
+	mu        sync.RWMutex
+	token     int64
+	since     time.Time
+	renewedAt time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewLeaderElector 创建领导选举器
+func NewLeaderElector(redisClient *redis.Client, name string, ttl time.Duration) *LeaderElector {
+	hostname, _ := os.Hostname()
+	return &LeaderElector{
+		redisClient: redisClient,
+		name:        name,
+		instanceID:  fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.New().String()[:8]),
+		ttl:         ttl,
+		done:        make(chan struct{}),
+	}
+}
+
+// SetTokenFloor 设置防护令牌下限的来源，需在 Start 之前调用。
+// 获取领导权时分配的令牌大于下限，Redis重启或数据丢失后令牌不会回退到存储层会拒绝的值
+func (e *LeaderElector) SetTokenFloor(floor TokenFloor) {
+	e.tokenFloor = floor
+}
+
+// Start 立即尝试获取一次领导权，然后在后台持续竞选和续期
+func (e *LeaderElector) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.tick(ctx)
+
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(e.ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				e.tick(ctx)
+			}
+		}
+	}()
+}
+
+// Stop 停止竞选，持有领导权时主动释放，其他实例无需等待租约过期
+func (e *LeaderElector) Stop() {
+	if e.cancel == nil {
+		return
+	}
+	e.cancel()
+	<-e.done
+
+	e.mu.Lock()
+	token := e.token
+	e.token = 0
+	e.mu.Unlock()
+
+	if token == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := releaseScript.Run(ctx, e.redisClient, []string{e.key()}, e.value(token)).Err(); err != nil {
+		log.Printf("Failed to release %s leadership: %v", e.name, err)
+	}
+}
+
+// IsLeader 当前实例是否为领导者
+func (e *LeaderElector) IsLeader() bool {
+	_, ok := e.Token()
+	return ok
+}
+
+// Token 获取当前的防护令牌，不是领导者或租约可能已过期时返回false
+func (e *LeaderElector) Token() (int64, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.token == 0 || time.Since(e.renewedAt) >= e.ttl {
+		return 0, false
+	}
+	return e.token, true
+}
+
+// Status 获取领导权状态
+func (e *LeaderElector) Status() LeaderStatus {
+	status := LeaderStatus{
+		Name:       e.name,
+		InstanceID: e.instanceID,
+	}
+	if token, ok := e.Token(); ok {
+		e.mu.RLock()
+		since := e.since
+		e.mu.RUnlock()
+		status.IsLeader = true
+		status.Token = token
+		status.Since = &since
+	}
+	return status
+}
+
+// Name 选举名称，同时用作防护令牌的作用域
+func (e *LeaderElector) Name() string {
+	return e.name
+}
+
+// tick 是领导者时续期，否则尝试获取领导权
+func (e *LeaderElector) tick(ctx context.Context) {
+	e.mu.RLock()
+	token := e.token
+	renewedAt := e.renewedAt
+	e.mu.RUnlock()
+
+	ttl := strconv.FormatInt(e.ttl.Milliseconds(), 10)
+	now := time.Now()
+
+	if token != 0 {
+		renewed, err := renewScript.Run(ctx, e.redisClient, []string{e.key()}, e.value(token), ttl).Int()
+		switch {
+		case err == nil && renewed == 1:
+			e.mu.Lock()
+			e.renewedAt = now
+			e.mu.Unlock()
+		case err == nil:
+			e.stepDown(token, "lease lost")
+		case time.Since(renewedAt) >= e.ttl:
+			e.stepDown(token, fmt.Sprintf("lease expired: %v", err))
+		default:
+			log.Printf("Failed to renew %s leadership: %v", e.name, err)
+		}
+		return
+	}
+
+	var floor int64
+	if e.tokenFloor != nil {
+		var err error
+		if floor, err = e.tokenFloor(ctx, e.name); err != nil {
+			// 无法确认下限时不获取领导权，避免分配会被拒绝的令牌
+			if ctx.Err() == nil {
+				log.Printf("Failed to get %s fencing token floor: %v", e.name, err)
+			}
+			return
+		}
+	}
+
+	acquired, err := acquireScript.Run(ctx, e.redisClient, []string{e.key(), e.key() + leaderTokenKeySuffix}, e.instanceID, ttl, floor).Int64()
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to acquire %s leadership: %v", e.name, err)
+		}
+		return
+	}
+	if acquired == 0 {
+		return
+	}
+
+	e.mu.Lock()
+	e.token = acquired
+	e.since = now
+	e.renewedAt = now
+	e.mu.Unlock()
+	log.Printf("Acquired %s leadership (instance %s, fencing token %d)", e.name, e.instanceID, acquired)
+}
+
+// stepDown 放弃领导权
+func (e *LeaderElector) stepDown(token int64, reason string) {
+	e.mu.Lock()
+	if e.token == token {
+		e.token = 0
+	}
+	e.mu.Unlock()
+	log.Printf("Lost %s leadership: %s", e.name, reason)
+}
+
+// key 领导者键
+func (e *LeaderElector) key() string {
+	return leaderKeyPrefix + e.name
+}
+
+// value 领导者键的值
+func (e *LeaderElector) value(token int64) string {
+	return strings.Join([]string{e.instanceID, strconv.FormatInt(token, 10)}, ":")
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLeaderElectorFencingTokens(t *testing.T) {
+	server, client := newTestRedis(t)
+	ctx := context.Background()
+	ttl := 10 * time.Second
+
+	first := NewLeaderElector(client, "cleanup", ttl)
+	second := NewLeaderElector(client, "cleanup", ttl)
+
+	first.Start()
+	token, ok := first.Token()
+	if !ok || token != 1 {
+		t.Fatalf("first Token() = %d, %v; want 1, true", token, ok)
+	}
+
+	// 领导权被占用时不能获取
+	second.tick(ctx)
+	if second.IsLeader() {
+		t.Fatal("second acquired leadership while first holds it")
+	}
+
+	// 主动释放后其他实例立即接替，防护令牌递增
+	first.Stop()
+	if first.IsLeader() {
+		t.Fatal("first is still leader after Stop")
+	}
+	second.tick(ctx)
+	if token, ok := second.Token(); !ok || token != 2 {
+		t.Fatalf("second Token() = %d, %v; want 2, true", token, ok)
+	}
+
+	// 租约过期后被接替，旧领导者续期失败后放弃领导权
+	third := NewLeaderElector(client, "cleanup", ttl)
+	server.FastForward(ttl + time.Second)
+	third.tick(ctx)
+	if token, ok := third.Token(); !ok || token != 3 {
+		t.Fatalf("third Token() = %d, %v; want 3, true", token, ok)
+	}
+	second.tick(ctx)
+	if second.IsLeader() {
+		t.Fatal("second kept leadership after its lease was taken over")
+	}
+}
+
+func TestLeaderElectorTokenFloorAfterRedisReset(t *testing.T) {
+	server, client := newTestRedis(t)
+	ctx := context.Background()
+	ttl := 10 * time.Second
+
+	// 存储层已经接受过令牌41
+	var floor int64 = 41
+	var floorErr error
+	elector := NewLeaderElector(client, "cleanup", ttl)
+	elector.SetTokenFloor(func(ctx context.Context, name string) (int64, error) {
+		if name != "cleanup" {
+			t.Errorf("floor requested for %q", name)
+		}
+		return floor, floorErr
+	})
+
+	// Redis数据丢失，计数器从0开始
+	server.FlushAll()
+	elector.tick(ctx)
+	if token, ok := elector.Token(); !ok || token != 42 {
+		t.Fatalf("Token() after reset = %d, %v; want 42, true", token, ok)
+	}
+	elector.stepDown(42, "test")
+	client.Del(ctx, elector.key())
+
+	// 计数器已经大于下限时照常递增
+	elector = NewLeaderElector(client, "cleanup", ttl)
+	elector.SetTokenFloor(func(context.Context, string) (int64, error) { return 1, nil })
+	elector.tick(ctx)
+	if token, ok := elector.Token(); !ok || token != 43 {
+		t.Fatalf("Token() = %d, %v; want 43, true", token, ok)
+	}
+	elector.stepDown(43, "test")
+	server.FlushAll()
+
+	// 无法读取下限时不获取领导权
+	floorErr = errors.New("database unavailable")
+	elector = NewLeaderElector(client, "cleanup", ttl)
+	elector.SetTokenFloor(func(context.Context, string) (int64, error) { return floor, floorErr })
+	elector.tick(ctx)
+	if elector.IsLeader() {
+		t.Fatal("acquired leadership without a token floor")
+	}
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher 密码哈希接口
+type PasswordHasher interface {
+	// Hash 对明文密码进行哈希
+	Hash(password string) (string, error)
+	// Verify 校验明文密码与存储值是否匹配
+	Verify(stored, password string) (bool, error)
+	// NeedsRehash 判断存储值是否需要重新哈希（明文遗留数据或参数过期）
+	NeedsRehash(stored string) bool
+}
+
+// BcryptHasher 基于bcrypt的密码哈希实现
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher 创建bcrypt哈希器，cost为0时使用默认值
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{cost: cost}
+}
+
+// Hash 对明文密码进行哈希
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify 校验密码，兼容历史遗留的明文存储
+func (h *BcryptHasher) Verify(stored, password string) (bool, error) {
+	if !isBcryptHash(stored) {
+		// 历史明文数据，使用常量时间比较
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1, nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(stored), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// NeedsRehash 明文或cost低于当前配置时需要重新哈希
+func (h *BcryptHasher) NeedsRehash(stored string) bool {
+	if !isBcryptHash(stored) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(stored))
+	if err != nil {
+		return true
+	}
+	return cost < h.cost
+}
+
+// isBcryptHash 判断是否为bcrypt哈希值
+func isBcryptHash(stored string) bool {
+	return len(stored) == 60 &&
+		(strings.HasPrefix(stored, "$2a$") || strings.HasPrefix(stored, "$2b$") || strings.HasPrefix(stored, "$2y$"))
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcryptHasherHashAndVerify(t *testing.T) {
+	hasher := NewBcryptHasher(bcrypt.MinCost)
+	hashed, err := hasher.Hash("secret123")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !isBcryptHash(hashed) {
+		t.Fatalf("Hash returned %q, want a bcrypt hash", hashed)
+	}
+
+	tests := []struct {
+		name     string
+		stored   string
+		password string
+		want     bool
+	}{
+		{"hash match", hashed, "secret123", true},
+		{"hash mismatch", hashed, "secret124", false},
+		{"hash empty password", hashed, "", false},
+		{"legacy plaintext match", "admin123", "admin123", true},
+		{"legacy plaintext mismatch", "admin123", "admin124", false},
+		{"legacy plaintext prefix", "admin123", "admin", false},
+		{"legacy plaintext longer", "admin123", "admin1234", false},
+		// 形如bcrypt但长度不对的值按明文比较，不会被当作哈希解析
+		{"truncated hash is plaintext", hashed[:59], hashed[:59], true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := hasher.Verify(tt.stored, tt.password)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Verify(%q, %q) = %v, want %v", tt.stored, tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBcryptHasherVerifyMalformedHash(t *testing.T) {
+	hasher := NewBcryptHasher(bcrypt.MinCost)
+	malformed := "$2a$99$" + strings.Repeat("x", 53)
+	if ok, err := hasher.Verify(malformed, "secret"); ok || err == nil {
+		t.Fatalf("Verify(malformed) = %v, %v, want false and an error", ok, err)
+	}
+}
+
+func TestBcryptHasherNeedsRehash(t *testing.T) {
+	hasher := NewBcryptHasher(bcrypt.MinCost + 1)
+	current, err := hasher.Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	weak, err := NewBcryptHasher(bcrypt.MinCost).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash(weak): %v", err)
+	}
+	stronger, err := NewBcryptHasher(bcrypt.MinCost + 2).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash(stronger): %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		stored string
+		want   bool
+	}{
+		{"legacy plaintext", "secret", true},
+		{"lower cost", weak, true},
+		{"current cost", current, false},
+		{"higher cost", stronger, false},
+		{"malformed cost", "$2a$zz$" + strings.Repeat("x", 53), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasher.NeedsRehash(tt.stored); got != tt.want {
+				t.Fatalf("NeedsRehash(%q) = %v, want %v", tt.stored, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBcryptHasherDefaultCost(t *testing.T) {
+	if cost := NewBcryptHasher(0).cost; cost != bcrypt.DefaultCost {
+		t.Fatalf("cost = %d, want %d", cost, bcrypt.DefaultCost)
+	}
+}
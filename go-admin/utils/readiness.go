@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"sync/atomic"
+)
+
+// Readiness 服务就绪状态。停止时先标记为未就绪，健康检查返回503，
+// 负载均衡据此摘除实例后再停止接受连接
+type Readiness struct {
+	draining atomic.Bool
+}
+
+// Drain 标记为未就绪
+func (r *Readiness) Drain() {
+	r.draining.Store(true)
+}
+
+// Ready 是否就绪
+func (r *Readiness) Ready() bool {
+	return !r.draining.Load()
+}
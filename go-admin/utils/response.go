@@ -31,6 +31,25 @@ func SuccessWithMessage(c *gin.Context, message string, data interface{}) {
 	})
 }
 
+// Accepted 202响应，请求已接受但尚未处理完成
+func Accepted(c *gin.Context, message string, data interface{}) {
+	c.JSON(http.StatusAccepted, Response{
+		Code:    http.StatusAccepted,
+		Message: message,
+		Data:    data,
+	})
+}
+
+// Conflict 409错误
+func Conflict(c *gin.Context, message string) {
+	Error(c, http.StatusConflict, message)
+}
+
+// Gone 410错误
+func Gone(c *gin.Context, message string) {
+	Error(c, http.StatusGone, message)
+}
+
 // Error 错误响应
 func Error(c *gin.Context, code int, message string) {
 	c.JSON(code, Response{
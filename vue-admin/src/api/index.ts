@@ -3,20 +3,6 @@ import { useAuthStore } from "@/stores/auth";
 // API基础URL
 const API_BASE_URL = "/api/v1";
 
-// 创建带认证的请求头
-const createAuthHeaders = () => {
-  const authStore = useAuthStore();
-  const headers: Record<string, string> = {
-    "Content-Type": "application/json",
-  };
-
-  if (authStore.token) {
-    headers["Authorization"] = `Bearer ${authStore.token}`;
-  }
-
-  return headers;
-};
-
 // 通用API请求函数
 export const apiRequest = async (
   endpoint: string,
@@ -25,16 +11,19 @@ export const apiRequest = async (
   const authStore = useAuthStore();
 
   const config: RequestInit = {
-    headers: createAuthHeaders(),
+    headers: {
+      "Content-Type": "application/json",
+    },
     ...options,
   };
 
   try {
-    const response = await fetch(`${API_BASE_URL}${endpoint}`, config);
-
-    // 如果返回401，说明token过期
+    // 访问令牌过期时自动刷新并重试，仍返回401说明登录已失效
+    const response = await authStore.authFetch(
+      `${API_BASE_URL}${endpoint}`,
+      config
+    );
     if (response.status === 401) {
-      authStore.logout();
       throw new Error("Token已过期，请重新登录");
     }
 
@@ -126,27 +115,25 @@ export const authApi = {
 // 图片相关API
 export const imageApi = {
   // 上传图片
-  uploadImage: (formData: FormData) => {
+  uploadImage: async (formData: FormData) => {
     const authStore = useAuthStore();
-    return fetch(`${API_BASE_URL}/images/upload`, {
-      method: "POST",
-      headers: {
-        Authorization: `Bearer ${authStore.token}`,
-      },
-      body: formData,
-    }).then(async (response) => {
-      if (response.status === 401) {
-        authStore.logout();
-        throw new Error("Token已过期，请重新登录");
+    const response = await authStore.authFetch(
+      `${API_BASE_URL}/images/upload`,
+      {
+        method: "POST",
+        body: formData,
       }
+    );
+    if (response.status === 401) {
+      throw new Error("Token已过期，请重新登录");
+    }
 
-      if (!response.ok) {
-        const errorData = await response.json().catch(() => ({}));
-        throw new Error(errorData.message || `请求失败: ${response.status}`);
-      }
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.message || `请求失败: ${response.status}`);
+    }
 
-      return await response.json();
-    });
+    return await response.json();
   },
 
   // 获取图片列表
@@ -165,6 +152,98 @@ export const imageApi = {
   // 根据图片码获取图片
   getImageByCode: (code: string) => apiGet(`/images/code/${code}`),
 
-  // 删除图片
+  // 删除图片（异步执行，返回删除任务ID）
   deleteImage: (id: number) => apiDelete(`/images/${id}`),
 };
+
+// 后台任务API
+export const taskApi = {
+  // 获取任务列表
+  getTasks: (params: {
+    page?: number;
+    pageSize?: number;
+    status?: string;
+    type?: string;
+    imageId?: number;
+  }) => {
+    const searchParams = new URLSearchParams();
+    if (params.page) searchParams.append("page", params.page.toString());
+    if (params.pageSize)
+      searchParams.append("page_size", params.pageSize.toString());
+    if (params.status) searchParams.append("status", params.status);
+    if (params.type) searchParams.append("type", params.type);
+    if (params.imageId)
+      searchParams.append("image_id", params.imageId.toString());
+
+    return apiGet(`/tasks?${searchParams.toString()}`);
+  },
+
+  // 获取任务详情
+  getTask: (id: string) => apiGet(`/tasks/${id}`),
+
+  // 取消等待处理的任务
+  cancelTask: (id: string) => apiPost(`/tasks/${id}/cancel`, {}),
+};
+
+// 实时事件
+export interface ServerEvent {
+  type: string;
+  user_id?: number;
+  task_id?: string;
+  task_type?: string;
+  image_id?: number;
+  image_code?: string;
+  message?: string;
+  time: string;
+}
+
+// 连接断开或服务端关闭后重新连接的等待时间（毫秒）
+const EVENT_RECONNECT_DELAY = 3000;
+
+export const eventApi = {
+  // 获取一次性的连接票据。EventSource无法设置请求头，票据代替访问令牌放在查询参数中
+  getTicket: (): Promise<{ data: { ticket: string; expires_in: number } }> =>
+    apiPost("/events/ticket", {}),
+
+  // 订阅实时事件，返回的函数用于关闭连接。
+  // 票据只能使用一次，断线或令牌过期（stream.closed）后重新获取票据再连接
+  subscribe: (types: string[], onEvent: (event: ServerEvent) => void) => {
+    let source: EventSource | null = null;
+    let closed = false;
+    let retryTimer: ReturnType<typeof setTimeout> | undefined;
+
+    const reconnect = () => {
+      source?.close();
+      source = null;
+      if (!closed) {
+        retryTimer = setTimeout(connect, EVENT_RECONNECT_DELAY);
+      }
+    };
+
+    const connect = async () => {
+      try {
+        const { data } = await eventApi.getTicket();
+        if (closed) return;
+        source = new EventSource(
+          `${API_BASE_URL}/events?ticket=${encodeURIComponent(data.ticket)}`
+        );
+        types.forEach((type) => {
+          source!.addEventListener(type, (e) => {
+            onEvent(JSON.parse((e as MessageEvent).data));
+          });
+        });
+        source.addEventListener("stream.closed", reconnect);
+        source.onerror = reconnect;
+      } catch {
+        reconnect();
+      }
+    };
+
+    connect();
+    return () => {
+      closed = true;
+      clearTimeout(retryTimer);
+      source?.close();
+    };
+  },
+};
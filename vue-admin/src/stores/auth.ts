@@ -10,20 +10,108 @@ export interface User {
 
 export interface LoginResponse {
   token: string;
+  refresh_token: string;
+  expires_in: number;
   user: User;
 }
 
+export interface TokenPair {
+  token: string;
+  refresh_token: string;
+  expires_in: number;
+}
+
 export const useAuthStore = defineStore("auth", () => {
   const token = ref<string | null>(localStorage.getItem("token"));
+  const refreshToken = ref<string | null>(
+    localStorage.getItem("refresh_token")
+  );
   const user = ref<User | null>(null);
   const router = useRouter();
 
+  // 正在进行的刷新请求，并发的401共用同一次刷新（刷新令牌只能使用一次）
+  let refreshing: Promise<boolean> | null = null;
+
   // 设置token
   const setToken = (newToken: string) => {
     token.value = newToken;
     localStorage.setItem("token", newToken);
   };
 
+  // 设置访问令牌和刷新令牌
+  const setTokens = (tokens: TokenPair) => {
+    setToken(tokens.token);
+    refreshToken.value = tokens.refresh_token;
+    localStorage.setItem("refresh_token", tokens.refresh_token);
+  };
+
+  // 清除本地登录状态并跳转到登录页
+  const clearSession = () => {
+    token.value = null;
+    refreshToken.value = null;
+    user.value = null;
+    localStorage.removeItem("token");
+    localStorage.removeItem("refresh_token");
+    router.push("/login");
+  };
+
+  // 使用刷新令牌换取新的令牌对
+  const refresh = (): Promise<boolean> => {
+    if (!refreshing) {
+      refreshing = (async () => {
+        if (!refreshToken.value) {
+          return false;
+        }
+        try {
+          const response = await fetch("/api/v1/auth/refresh", {
+            method: "POST",
+            headers: {
+              "Content-Type": "application/json",
+            },
+            body: JSON.stringify({ refresh_token: refreshToken.value }),
+          });
+          if (!response.ok) {
+            return false;
+          }
+          const data = await response.json();
+          setTokens(data.data);
+          return true;
+        } catch (error) {
+          console.error("Refresh token error:", error);
+          return false;
+        }
+      })().finally(() => {
+        refreshing = null;
+      });
+    }
+    return refreshing;
+  };
+
+  // 携带访问令牌发起请求，返回401时刷新令牌后重试一次；刷新失败时清除登录状态
+  const authFetch = async (
+    input: string,
+    init: RequestInit = {}
+  ): Promise<Response> => {
+    const send = () => {
+      const headers = new Headers(init.headers);
+      if (token.value) {
+        headers.set("Authorization", `Bearer ${token.value}`);
+      }
+      return fetch(input, { ...init, headers });
+    };
+
+    let response = await send();
+    if (response.status === 401) {
+      if (await refresh()) {
+        response = await send();
+      }
+      if (response.status === 401) {
+        clearSession();
+      }
+    }
+    return response;
+  };
+
   // 设置用户信息
   const setUser = (userInfo: User) => {
     user.value = userInfo;
@@ -51,7 +139,7 @@ export const useAuthStore = defineStore("auth", () => {
       const data = await response.json();
       const loginData: LoginResponse = data.data;
 
-      setToken(loginData.token);
+      setTokens(loginData);
       setUser(loginData.user);
 
       return true;
@@ -61,12 +149,21 @@ export const useAuthStore = defineStore("auth", () => {
     }
   };
 
-  // 登出
-  const logout = () => {
-    token.value = null;
-    user.value = null;
-    localStorage.removeItem("token");
-    router.push("/login");
+  // 登出，同时吊销服务端会话
+  const logout = async () => {
+    if (token.value) {
+      try {
+        await fetch("/api/v1/auth/logout", {
+          method: "POST",
+          headers: {
+            Authorization: `Bearer ${token.value}`,
+          },
+        });
+      } catch (error) {
+        console.error("Logout error:", error);
+      }
+    }
+    clearSession();
   };
 
   // 检查token是否有效
@@ -76,11 +173,7 @@ export const useAuthStore = defineStore("auth", () => {
     }
 
     try {
-      const response = await fetch("/api/v1/auth/profile", {
-        headers: {
-          Authorization: `Bearer ${token.value}`,
-        },
-      });
+      const response = await authFetch("/api/v1/auth/profile");
 
       if (response.ok) {
         const data = await response.json();
@@ -88,12 +181,12 @@ export const useAuthStore = defineStore("auth", () => {
         return true;
       } else {
         // token无效，清除本地存储
-        logout();
+        clearSession();
         return false;
       }
     } catch (error) {
       console.error("Auth check error:", error);
-      logout();
+      clearSession();
       return false;
     }
   };
@@ -105,35 +198,36 @@ export const useAuthStore = defineStore("auth", () => {
     }
 
     try {
-      const response = await fetch("/api/v1/auth/profile", {
-        headers: {
-          Authorization: `Bearer ${token.value}`,
-        },
-      });
+      const response = await authFetch("/api/v1/auth/profile");
 
       if (response.ok) {
         const data = await response.json();
         setUser(data.data);
         return data.data;
       } else {
-        logout();
+        clearSession();
         return null;
       }
     } catch (error) {
       console.error("Get profile error:", error);
-      logout();
+      clearSession();
       return null;
     }
   };
 
   return {
     token,
+    refreshToken,
     user,
     login,
     logout,
+    refresh,
+    authFetch,
+    clearSession,
     checkAuth,
     getProfile,
     setToken,
+    setTokens,
     setUser,
   };
 });